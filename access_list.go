@@ -0,0 +1,188 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	dbplugin "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	"github.com/hashicorp/vault/sdk/helper/template"
+	"go.mongodb.org/atlas/mongodbatlas"
+)
+
+const (
+	// labelAccessList is attached to a database user once per access list
+	// entry that was added for its lease. The labels act as reference counts
+	// so entries shared by several leases are only removed with the last one.
+	labelAccessList = "vault-access-list"
+
+	// accessListComment marks the access list entries added by the plugin.
+	// Entries without it were created out-of-band and are never modified.
+	accessListComment = "Managed by Vault database secrets engine"
+
+	// Atlas refuses temporary access list entries that expire more than a
+	// week in the future.
+	maxAccessListLifetime = 7 * 24 * time.Hour
+)
+
+// renderAccessList expands templated access list entries from a creation
+// statement and normalizes them to the form Atlas uses as entry identifiers.
+func renderAccessList(entries []string, metadata dbplugin.UsernameMetadata) ([]string, error) {
+	var rendered []string
+	for _, entry := range entries {
+		if strings.Contains(entry, "{{") {
+			tmpl, err := template.NewTemplate(template.Template(entry))
+			if err != nil {
+				return nil, fmt.Errorf("invalid access_list template %q: %w", entry, err)
+			}
+			entry, err = tmpl.Generate(metadata)
+			if err != nil {
+				return nil, fmt.Errorf("unable to render access_list template: %w", err)
+			}
+		}
+
+		entry = strings.TrimSpace(entry)
+		if strings.Contains(entry, "/") {
+			_, ipNet, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid access_list entry %q: %w", entry, err)
+			}
+			entry = ipNet.String()
+		} else if ip := net.ParseIP(entry); ip != nil {
+			entry = ip.String()
+		} else {
+			return nil, fmt.Errorf("invalid access_list entry %q: not an IP address or CIDR block", entry)
+		}
+		rendered = append(rendered, entry)
+	}
+	return rendered, nil
+}
+
+// accessListLabels returns the labels recording the entries on a user.
+func accessListLabels(entries []string) []mongodbatlas.Label {
+	var labels []mongodbatlas.Label
+	for _, entry := range entries {
+		labels = append(labels, mongodbatlas.Label{Key: labelAccessList, Value: entry})
+	}
+	return labels
+}
+
+// userAccessList returns the access list entries recorded on a user.
+func userAccessList(user *mongodbatlas.DatabaseUser) []string {
+	var entries []string
+	for _, label := range user.Labels {
+		if label.Key == labelAccessList {
+			entries = append(entries, label.Value)
+		}
+	}
+	return entries
+}
+
+// addAccessList adds the entries to the project access list so they expire
+// with the lease. Entries the plugin added for other leases are extended if
+// they would expire sooner; entries created out-of-band are left untouched.
+func (c *mongoDBAtlasConnectionProducer) addAccessList(ctx context.Context, client *mongodbatlas.Client, projectID string, entries []string, expiration time.Time) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	unlock := c.lockProject(projectID)
+	defer unlock()
+
+	deleteAfter := expiration.UTC()
+	if maxDeleteAfter := time.Now().UTC().Add(maxAccessListLifetime); expiration.IsZero() || deleteAfter.After(maxDeleteAfter) {
+		deleteAfter = maxDeleteAfter
+	}
+
+	var requests []*mongodbatlas.ProjectIPAccessList
+	for _, entry := range entries {
+		existing, _, err := client.ProjectIPAccessList.Get(ctx, projectID, entry)
+		switch {
+		case isAtlasNotFound(err):
+		case err != nil:
+			return fmt.Errorf("error reading access list entry %q: %w", entry, err)
+		case existing.Comment != accessListComment:
+			continue
+		default:
+			current, err := time.Parse(time.RFC3339, existing.DeleteAfterDate)
+			if err == nil && !current.Before(deleteAfter) {
+				continue
+			}
+		}
+
+		request := &mongodbatlas.ProjectIPAccessList{
+			Comment:         accessListComment,
+			DeleteAfterDate: deleteAfter.Format(time.RFC3339),
+		}
+		if strings.Contains(entry, "/") {
+			request.CIDRBlock = entry
+		} else {
+			request.IPAddress = entry
+		}
+		requests = append(requests, request)
+	}
+
+	if len(requests) == 0 {
+		return nil
+	}
+
+	_, _, err := client.ProjectIPAccessList.Create(ctx, projectID, requests)
+	if err != nil {
+		return fmt.Errorf("error adding access list entries: %w", err)
+	}
+	return nil
+}
+
+// releaseAccessList removes the entries added by the plugin that are no
+// longer referenced by any user other than the given one.
+func (c *mongoDBAtlasConnectionProducer) releaseAccessList(ctx context.Context, client *mongodbatlas.Client, projectID, username string, entries []string) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	unlock := c.lockProject(projectID)
+	defer unlock()
+
+	users, err := listUsers(ctx, client, projectID)
+	if err != nil {
+		return fmt.Errorf("error listing users to release access list entries: %w", err)
+	}
+
+	referenced := make(map[string]struct{})
+	for i := range users {
+		if users[i].Username == username {
+			continue
+		}
+		for _, entry := range userAccessList(&users[i]) {
+			referenced[entry] = struct{}{}
+		}
+	}
+
+	for _, entry := range entries {
+		if _, ok := referenced[entry]; ok {
+			continue
+		}
+
+		existing, _, err := client.ProjectIPAccessList.Get(ctx, projectID, entry)
+		if isAtlasNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("error reading access list entry %q: %w", entry, err)
+		}
+		if existing.Comment != accessListComment {
+			continue
+		}
+
+		_, err = client.ProjectIPAccessList.Delete(ctx, projectID, entry)
+		if err != nil && !isAtlasNotFound(err) {
+			return fmt.Errorf("error removing access list entry %q: %w", entry, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	dbplugin "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/atlas/mongodbatlas"
+)
+
+func TestRenderAccessList(t *testing.T) {
+	entries, err := renderAccessList([]string{
+		"203.0.113.5",
+		"10.1.2.3/16",
+		`{{ if eq .RoleName "ci" }}198.51.100.0/24{{ else }}192.0.2.1{{ end }}`,
+	}, dbplugin.UsernameMetadata{RoleName: "ci"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"203.0.113.5", "10.1.0.0/16", "198.51.100.0/24"}, entries)
+
+	_, err = renderAccessList([]string{"example.com"}, dbplugin.UsernameMetadata{})
+	require.ErrorContains(t, err, "not an IP address or CIDR block")
+}
+
+func TestAccessList_Lifecycle(t *testing.T) {
+	atlas := newFakeAtlas(t)
+	atlas.putAccessListEntry("project", &mongodbatlas.ProjectIPAccessList{
+		IPAddress: "192.0.2.10",
+		Comment:   "office",
+	})
+
+	db := atlas.newTestDB(t, nil)
+
+	statement := `{"roles": [{"databaseName":"admin","roleName":"read"}], "access_list": ["198.51.100.0/24", "192.0.2.10"]}`
+	newUser := func(expiration time.Time) string {
+		resp, err := db.NewUser(context.Background(), dbplugin.NewUserRequest{
+			UsernameConfig: dbplugin.UsernameMetadata{DisplayName: "token", RoleName: "app"},
+			Statements:     dbplugin.Statements{Commands: []string{statement}},
+			Password:       "password",
+			Expiration:     expiration,
+		})
+		require.NoError(t, err)
+		return resp.Username
+	}
+
+	soon := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	later := soon.Add(time.Hour)
+
+	first := newUser(soon)
+	entry := atlas.accessListEntry("project", "198.51.100.0/24")
+	require.NotNil(t, entry)
+	require.Equal(t, accessListComment, entry.Comment)
+	require.Equal(t, soon.Format(time.RFC3339), entry.DeleteAfterDate)
+
+	// Pre-existing entries are never modified
+	require.Equal(t, "office", atlas.accessListEntry("project", "192.0.2.10").Comment)
+	require.Empty(t, atlas.accessListEntry("project", "192.0.2.10").DeleteAfterDate)
+
+	// A second lease sharing the entry extends it
+	second := newUser(later)
+	require.Equal(t, later.Format(time.RFC3339), atlas.accessListEntry("project", "198.51.100.0/24").DeleteAfterDate)
+
+	// Renewal extends it further
+	renewed := later.Add(time.Hour)
+	_, err := db.UpdateUser(context.Background(), dbplugin.UpdateUserRequest{
+		Username:   first,
+		Expiration: &dbplugin.ChangeExpiration{NewExpiration: renewed},
+	})
+	require.NoError(t, err)
+	require.Equal(t, renewed.Format(time.RFC3339), atlas.accessListEntry("project", "198.51.100.0/24").DeleteAfterDate)
+
+	// The entry is only removed with the last lease referencing it
+	_, err = db.DeleteUser(context.Background(), dbplugin.DeleteUserRequest{Username: first})
+	require.NoError(t, err)
+	require.NotNil(t, atlas.accessListEntry("project", "198.51.100.0/24"))
+
+	_, err = db.DeleteUser(context.Background(), dbplugin.DeleteUserRequest{Username: second})
+	require.NoError(t, err)
+	require.Nil(t, atlas.accessListEntry("project", "198.51.100.0/24"))
+	require.NotNil(t, atlas.accessListEntry("project", "192.0.2.10"))
+}
+
+func TestUpdateUser_RenewWithoutAccessList(t *testing.T) {
+	atlas := newFakeAtlas(t)
+	atlas.putUser("project", &mongodbatlas.DatabaseUser{
+		Username:     "v-app",
+		DatabaseName: "admin",
+		Labels:       managedUserLabels("app"),
+	})
+	db := atlas.newTestDB(t, nil)
+
+	renew := func(username string) error {
+		_, err := db.UpdateUser(context.Background(), dbplugin.UpdateUserRequest{
+			Username:   username,
+			Expiration: &dbplugin.ChangeExpiration{NewExpiration: time.Now().Add(time.Hour)},
+		})
+		return err
+	}
+
+	// Nothing expires without access list entries, and users deleted out of
+	// band have nothing left to renew
+	require.NoError(t, renew("v-app"))
+	require.NoError(t, renew("v-missing"))
+	for _, request := range atlas.requests {
+		require.Regexp(t, "^GET ", request)
+	}
+}
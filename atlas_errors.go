@@ -0,0 +1,166 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.mongodb.org/atlas/mongodbatlas"
+)
+
+// Atlas API error codes the plugin reacts to.
+// See https://www.mongodb.com/docs/atlas/reference/api-errors/
+const (
+	atlasErrUserAlreadyExists     = "USER_ALREADY_EXISTS"
+	atlasErrUsernameNotFound      = "USERNAME_NOT_FOUND"
+	atlasErrNotOnAccessList       = "IP_ADDRESS_NOT_ON_ACCESS_LIST"
+	atlasErrOrgRequiresAccessList = "ORG_REQUIRES_ACCESS_LIST"
+	atlasErrRequiresAccessList    = "RESOURCE_REQUIRES_ACCESS_LIST"
+)
+
+// atlasRequestIDHeader identifies an Atlas API request when contacting
+// MongoDB support.
+const atlasRequestIDHeader = "X-Request-Id"
+
+// atlasErrorKind classifies Atlas API errors by what an operator can do
+// about them.
+type atlasErrorKind string
+
+const (
+	atlasErrorAuth       atlasErrorKind = "authentication"
+	atlasErrorPermission atlasErrorKind = "permission"
+	atlasErrorAccessList atlasErrorKind = "access list"
+	atlasErrorNotFound   atlasErrorKind = "not found"
+	atlasErrorConflict   atlasErrorKind = "conflict"
+	atlasErrorQuota      atlasErrorKind = "quota"
+	atlasErrorTransient  atlasErrorKind = "transient"
+)
+
+var atlasErrorHints = map[atlasErrorKind]string{
+	atlasErrorAuth:       "check public_key and private_key; the API key may have been deleted or rotated",
+	atlasErrorPermission: "grant the API key the Project Owner role on the project",
+	atlasErrorAccessList: "add the address Vault connects to Atlas from to the API key's access list",
+	atlasErrorNotFound:   "check that the project and user exist and that the project ID is correct",
+	atlasErrorConflict:   "the user already exists; it may have been created outside Vault or by an earlier attempt",
+	atlasErrorQuota:      "the project reached an Atlas limit; remove unused users or ask MongoDB support to raise it",
+	atlasErrorTransient:  "Atlas is unavailable or rate limiting requests; retry later",
+}
+
+// atlasError is a classified Atlas API error. It wraps the error it was
+// made from, so the Atlas response remains reachable with errors.As.
+type atlasError struct {
+	kind      atlasErrorKind
+	code      string
+	requestID string
+	err       error
+}
+
+func (e *atlasError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s; Atlas %s error", e.err, e.kind)
+	if e.requestID != "" {
+		fmt.Fprintf(&b, " (request ID %s)", e.requestID)
+	}
+	fmt.Fprintf(&b, ": %s", atlasErrorHints[e.kind])
+	return b.String()
+}
+
+func (e *atlasError) Unwrap() error {
+	return e.err
+}
+
+// classifyAtlasError wraps an error caused by an Atlas API response in an
+// atlasError. Other errors, and responses that don't fall in any class, are
+// returned unchanged.
+func classifyAtlasError(err error) error {
+	errResp, ok := atlasErrorResponse(err)
+	if !ok {
+		return err
+	}
+	var existing *atlasError
+	if errors.As(err, &existing) {
+		return err
+	}
+
+	kind, ok := atlasKind(errResp.ErrorCode, atlasStatusCode(err))
+	if !ok {
+		return err
+	}
+
+	classified := &atlasError{
+		kind: kind,
+		code: errResp.ErrorCode,
+		err:  err,
+	}
+	if errResp.Response != nil {
+		classified.requestID = errResp.Response.Header.Get(atlasRequestIDHeader)
+	}
+	return classified
+}
+
+func atlasKind(code string, status int) (atlasErrorKind, bool) {
+	switch code {
+	case atlasErrNotOnAccessList, atlasErrOrgRequiresAccessList, atlasErrRequiresAccessList:
+		return atlasErrorAccessList, true
+	case atlasErrUserAlreadyExists:
+		return atlasErrorConflict, true
+	case atlasErrUsernameNotFound:
+		return atlasErrorNotFound, true
+	}
+	// Atlas reports exhausted limits with codes such as *_LIMIT_EXCEEDED
+	if strings.HasSuffix(code, "_EXCEEDED") {
+		return atlasErrorQuota, true
+	}
+
+	switch {
+	case status == http.StatusUnauthorized:
+		return atlasErrorAuth, true
+	case status == http.StatusForbidden:
+		return atlasErrorPermission, true
+	case status == http.StatusNotFound:
+		return atlasErrorNotFound, true
+	case status == http.StatusConflict:
+		return atlasErrorConflict, true
+	case status == http.StatusTooManyRequests || status >= http.StatusInternalServerError:
+		return atlasErrorTransient, true
+	}
+	return "", false
+}
+
+// atlasErrorResponse returns the Atlas API error wrapped in err, if any.
+func atlasErrorResponse(err error) (*mongodbatlas.ErrorResponse, bool) {
+	var errResp *mongodbatlas.ErrorResponse
+	if !errors.As(err, &errResp) {
+		return nil, false
+	}
+	return errResp, true
+}
+
+// atlasStatusCode returns the HTTP status code of an Atlas API error, or zero.
+func atlasStatusCode(err error) int {
+	errResp, ok := atlasErrorResponse(err)
+	if !ok {
+		return 0
+	}
+	if errResp.Response != nil {
+		return errResp.Response.StatusCode
+	}
+	return errResp.HTTPCode
+}
+
+// isAtlasNotFound reports whether err is an Atlas API 404 response.
+func isAtlasNotFound(err error) bool {
+	errResp, ok := atlasErrorResponse(err)
+	return ok && (errResp.ErrorCode == atlasErrUsernameNotFound || atlasStatusCode(err) == http.StatusNotFound)
+}
+
+// isAtlasConflict reports whether err is an Atlas API response saying the
+// user being created already exists.
+func isAtlasConflict(err error) bool {
+	errResp, ok := atlasErrorResponse(err)
+	return ok && (errResp.ErrorCode == atlasErrUserAlreadyExists || atlasStatusCode(err) == http.StatusConflict)
+}
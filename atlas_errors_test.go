@@ -0,0 +1,73 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	dbplugin "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/atlas/mongodbatlas"
+)
+
+func TestClassifyAtlasError(t *testing.T) {
+	tests := map[string]struct {
+		status int
+		code   string
+		kind   atlasErrorKind
+	}{
+		"auth":                  {http.StatusUnauthorized, "", atlasErrorAuth},
+		"permission":            {http.StatusForbidden, "USER_UNAUTHORIZED", atlasErrorPermission},
+		"access list":           {http.StatusForbidden, atlasErrNotOnAccessList, atlasErrorAccessList},
+		"org access list":       {http.StatusForbidden, atlasErrOrgRequiresAccessList, atlasErrorAccessList},
+		"not found":             {http.StatusNotFound, "GROUP_NOT_FOUND", atlasErrorNotFound},
+		"conflict":              {http.StatusConflict, "DUPLICATE_DATABASE_USER", atlasErrorConflict},
+		"quota":                 {http.StatusBadRequest, "DATABASE_USER_LIMIT_EXCEEDED", atlasErrorQuota},
+		"rate limited":          {http.StatusTooManyRequests, "RATE_LIMITED", atlasErrorTransient},
+		"service unavailable":   {http.StatusServiceUnavailable, "", atlasErrorTransient},
+		"internal server error": {http.StatusInternalServerError, "UNEXPECTED_ERROR", atlasErrorTransient},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			atlas := newFakeAtlas(t)
+			atlas.reject = func(r *http.Request) (int, string) {
+				if r.Method == http.MethodPost {
+					return tt.status, tt.code
+				}
+				return 0, ""
+			}
+			db := atlas.newTestDB(t, nil)
+
+			_, err := db.NewUser(context.Background(), dbplugin.NewUserRequest{
+				Statements: dbplugin.Statements{Commands: []string{testMongoDBAtlasRole}},
+				Password:   "password",
+			})
+
+			var classified *atlasError
+			require.ErrorAs(t, err, &classified)
+			require.Equal(t, tt.kind, classified.kind)
+			require.Equal(t, tt.code, classified.code)
+			require.Regexp(t, `^req-\d+$`, classified.requestID)
+			require.ErrorContains(t, err, "request ID "+classified.requestID)
+			require.ErrorContains(t, err, atlasErrorHints[tt.kind])
+
+			// The Atlas response stays reachable
+			var errResp *mongodbatlas.ErrorResponse
+			require.ErrorAs(t, err, &errResp)
+			require.Equal(t, tt.status, atlasStatusCode(err))
+		})
+	}
+}
+
+func TestClassifyAtlasError_Unclassified(t *testing.T) {
+	err := errors.New("connection refused")
+	require.Same(t, err, classifyAtlasError(err))
+
+	_, ok := atlasKind("INVALID_ATTRIBUTE", http.StatusBadRequest)
+	require.False(t, ok)
+}
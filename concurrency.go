@@ -0,0 +1,136 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// concurrencyConfig caps how many Atlas API requests are in flight per
+// project. Atlas rate limits requests per project, so bursts of leases
+// queue in the plugin instead of failing with rate limit errors.
+type concurrencyConfig struct {
+	MaxConcurrentRequestsPerProject int `json:"max_concurrent_requests_per_project" structs:"max_concurrent_requests_per_project" mapstructure:"max_concurrent_requests_per_project"`
+}
+
+func (c concurrencyConfig) validate() error {
+	if c.MaxConcurrentRequestsPerProject < 0 {
+		return errors.New("max_concurrent_requests_per_project must not be negative")
+	}
+	return nil
+}
+
+// projectLimiter hands out a fixed number of request slots per project.
+type projectLimiter struct {
+	limit int
+
+	mu    sync.Mutex
+	slots map[string]chan struct{}
+}
+
+func newProjectLimiter(limit int) *projectLimiter {
+	return &projectLimiter{
+		limit: limit,
+		slots: make(map[string]chan struct{}),
+	}
+}
+
+// acquire waits for a free slot in the project and returns the func that
+// frees it again.
+func (l *projectLimiter) acquire(ctx context.Context, projectID string) (func(), error) {
+	l.mu.Lock()
+	slots, ok := l.slots[projectID]
+	if !ok {
+		slots = make(chan struct{}, l.limit)
+		l.slots[projectID] = slots
+	}
+	l.mu.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// limitTransport holds a slot of the project a request is for while the
+// request is in flight. Requests that aren't scoped to a project pass
+// through.
+type limitTransport struct {
+	base    http.RoundTripper
+	limiter *projectLimiter
+}
+
+func (t *limitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	projectID := requestProject(req.URL.Path)
+	if projectID == "" {
+		return t.base.RoundTrip(req)
+	}
+
+	release, err := t.limiter.acquire(req.Context(), projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return t.base.RoundTrip(req)
+}
+
+// requestProject returns the project ID in an Atlas API path, e.g.
+// .../groups/{GROUP-ID}/databaseUsers.
+func requestProject(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if segment == "groups" && i+1 < len(segments) && segments[i+1] != "byName" {
+			return segments[i+1]
+		}
+	}
+	return ""
+}
+
+// projectLocks hands out a mutex per project. They are shared by every
+// state of a connection, so operations started before and after a
+// re-initialization are still serialized.
+type projectLocks struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+func (p *projectLocks) lock(projectID string) func() {
+	p.mu.Lock()
+	if p.locks == nil {
+		p.locks = make(map[string]*sync.Mutex)
+	}
+	lock, ok := p.locks[projectID]
+	if !ok {
+		lock = &sync.Mutex{}
+		p.locks[projectID] = lock
+	}
+	p.mu.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
+// sharedProjectLocks returns the state's project locks. c.mu must be held.
+func (c *connectionState) sharedProjectLocks() *projectLocks {
+	if c.projectLocks == nil {
+		c.projectLocks = &projectLocks{}
+	}
+	return c.projectLocks
+}
+
+// lockProject serializes changes to a project's access list, since entries
+// are added and released based on what was read before.
+func (c *mongoDBAtlasConnectionProducer) lockProject(projectID string) func() {
+	c.mu.Lock()
+	locks := c.sharedProjectLocks()
+	c.mu.Unlock()
+
+	return locks.lock(projectID)
+}
@@ -0,0 +1,157 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	dbplugin "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewUser_Concurrent(t *testing.T) {
+	atlas := newFakeAtlas(t)
+	atlas.latency = 10 * time.Millisecond
+	db := atlas.newTestDB(t, map[string]interface{}{
+		"max_concurrent_requests_per_project": 3,
+	})
+
+	const leases = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, leases)
+	for i := 0; i < leases; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := db.NewUser(context.Background(), dbplugin.NewUserRequest{
+				UsernameConfig: dbplugin.UsernameMetadata{DisplayName: "token", RoleName: "ci"},
+				Statements:     dbplugin.Statements{Commands: []string{testMongoDBAtlasRole}},
+				Password:       "password",
+			})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	require.Len(t, atlas.users["project"], leases)
+
+	// Leases are created in parallel, up to the per-project limit
+	require.Greater(t, atlas.maxInFlight, 1)
+	require.LessOrEqual(t, atlas.maxInFlight, 3)
+}
+
+func TestNewUser_ConcurrentQuota(t *testing.T) {
+	atlas := newFakeAtlas(t)
+	atlas.latency = 5 * time.Millisecond
+	db := atlas.newTestDB(t, map[string]interface{}{
+		"max_users_per_project": 3,
+	})
+
+	const leases = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, leases)
+	for i := 0; i < leases; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := db.NewUser(context.Background(), dbplugin.NewUserRequest{
+				UsernameConfig: dbplugin.UsernameMetadata{DisplayName: "token", RoleName: "ci"},
+				Statements:     dbplugin.Statements{Commands: []string{testMongoDBAtlasRole}},
+				Password:       "password",
+			})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	var failed int
+	for err := range errs {
+		if err != nil {
+			require.ErrorContains(t, err, "quota exceeded")
+			failed++
+		}
+	}
+	require.Equal(t, leases-3, failed)
+	require.Len(t, atlas.users["project"], 3)
+}
+
+func TestRequestProject(t *testing.T) {
+	tests := map[string]string{
+		"/api/atlas/v1.0/groups/p1/databaseUsers/admin/v-user": "p1",
+		"/api/atlas/v1.0/groups/p1":                            "p1",
+		"/api/atlas/v1.0/groups/byName/prod":                   "",
+		"/api/atlas/v1.0/groups":                               "",
+		"/api/atlas/v1.0/orgs/o1/groups":                       "",
+	}
+	for path, want := range tests {
+		require.Equal(t, want, requestProject(path), path)
+	}
+}
+
+func TestInitialize_InvalidConcurrencyLimit(t *testing.T) {
+	db := new()
+	_, err := db.Initialize(context.Background(), dbplugin.InitializeRequest{
+		Config: map[string]interface{}{
+			"public_key":                          "public",
+			"private_key":                         "private",
+			"project_id":                          "project",
+			"max_concurrent_requests_per_project": -1,
+		},
+	})
+	require.ErrorContains(t, err, "max_concurrent_requests_per_project must not be negative")
+}
+
+func TestInitialize_KeepsProjectLocksAndLimiter(t *testing.T) {
+	atlas := newFakeAtlas(t)
+	config := map[string]interface{}{
+		"public_key":                          "public",
+		"private_key":                         "private-key",
+		"project_id":                          "project",
+		"max_concurrent_requests_per_project": 3,
+	}
+	db := atlas.newTestDB(t, config)
+	reinitialize := func() {
+		t.Helper()
+		_, err := db.Initialize(context.Background(), dbplugin.InitializeRequest{Config: config})
+		require.NoError(t, err)
+	}
+
+	unlock := db.lockProject("project")
+	limiter := db.state().limiter
+
+	reinitialize()
+	require.Same(t, limiter, db.state().limiter)
+
+	// The lock taken before reinitializing still holds off the new state
+	locked := make(chan struct{})
+	go func() {
+		db.lockProject("project")()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+		t.Fatal("project lock was not kept across reinitialization")
+	case <-time.After(20 * time.Millisecond):
+	}
+	unlock()
+	<-locked
+
+	// A new limit takes a new limiter
+	config["max_concurrent_requests_per_project"] = 5
+	reinitialize()
+	require.NotSame(t, limiter, db.state().limiter)
+	require.Equal(t, 5, db.state().limiter.limit)
+
+	config["max_concurrent_requests_per_project"] = 0
+	reinitialize()
+	require.Nil(t, db.state().limiter)
+}
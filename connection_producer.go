@@ -6,7 +6,15 @@ package mongodbatlas
 import (
 	"context"
 	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"reflect"
 	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-secure-stdlib/parseutil"
 
 	dbplugin "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
 	"github.com/hashicorp/vault/sdk/database/helper/connutil"
@@ -15,6 +23,7 @@ import (
 	"github.com/mitchellh/mapstructure"
 	"github.com/mongodb-forks/digest"
 	"go.mongodb.org/atlas/mongodbatlas"
+	"golang.org/x/sync/singleflight"
 )
 
 const (
@@ -23,55 +32,252 @@ const (
 	//  Vault version information via the plugin environment.
 	userAgentVaultVersion = "1.13.0"
 	userAgentPluginName   = "database-mongodbatlas"
+
+	// clientBuildTimeout bounds building the default client, which isn't
+	// tied to the request of any one of the callers waiting for it.
+	clientBuildTimeout = 30 * time.Second
 )
 
+// mongoDBAtlasConnectionProducer guards the connection state. Initialize
+// builds a fresh state and swaps it in as a whole once it is valid. The lock
+// is only held to swap or read the state, so operations each see one
+// consistent configuration without serializing their Atlas calls.
 type mongoDBAtlasConnectionProducer struct {
+	*connectionState
+	sync.Mutex
+}
+
+// connectionState is the configuration and cached clients of a connection.
+type connectionState struct {
 	PublicKey  string `json:"public_key" structs:"public_key" mapstructure:"public_key"`
 	PrivateKey string `json:"private_key" structs:"private_key" mapstructure:"private_key"`
 	ProjectID  string `json:"project_id" structs:"project_id" mapstructure:"project_id"`
 
+	CustomerX509CAs []string `json:"customer_x509_cas" structs:"customer_x509_cas" mapstructure:"customer_x509_cas"`
+
+	projectNameConfig  `mapstructure:",squash"`
+	credentialsConfig  `mapstructure:",squash"`
+	secondaryKeyConfig `mapstructure:",squash"`
+
+	externalCredentialsConfig `mapstructure:",squash"`
+
+	subjectPolicy    `mapstructure:",squash"`
+	rolePolicy       `mapstructure:",squash"`
+	quotaConfig      `mapstructure:",squash"`
+	ownershipConfig  `mapstructure:",squash"`
+	deploymentConfig `mapstructure:",squash"`
+	verifyConfig     `mapstructure:",squash"`
+	sessionConfig    `mapstructure:",squash"`
+	rotationConfig   `mapstructure:",squash"`
+
+	projectOverrideConfig `mapstructure:",squash"`
+	concurrencyConfig     `mapstructure:",squash"`
+	timeoutConfig         `mapstructure:",squash"`
+
 	Initialized bool
 	RawConfig   map[string]interface{}
 	Type        string
-	client      *mongodbatlas.Client
-	sync.Mutex
+	logger      hclog.Logger
+
+	// mu guards the clients and caches below, as well as the API keys and
+	// project ID, which change as external credentials are reloaded and
+	// project_name is resolved.
+	mu sync.Mutex
+
+	// clientFlight builds the default client once for concurrent callers.
+	clientFlight singleflight.Group
+	client       *mongodbatlas.Client
+
+	// limiter bounds the Atlas requests in flight per project, and
+	// projectLocks serialize changes to each project's access list.
+	limiter      *projectLimiter
+	projectLocks *projectLocks
+
+	userCounts map[string]*managedUserCount
+
+	// rotatedPasswords holds the last verified password set for each user
+	// with two-phase rotation, so a failed rotation can be rolled back.
+	rotatedPasswords map[string]string
+
+	// userProjectIDs caches the project each user was found or created in.
+	userProjectIDs map[string]string
+
+	// resolvedProjectID is the project ID that project_name resolved to.
+	resolvedProjectID string
+
+	// credentialClients caches a client per named credential set, and
+	// userCredentials the credential set each user was created with.
+	credentialClients map[string]*mongodbatlas.Client
+	userCredentials   map[string]string
+
+	// failover switches between the primary and secondary API keys.
+	failover *failoverTransport
+
+	// credentialsCheckedAt is when external credential sources were last read.
+	credentialsCheckedAt time.Time
+
+	// These override the Atlas API endpoint, polling intervals and how
+	// clusters are reached. They are only set by tests.
+	baseURL                 string
+	deploymentPollInterval  time.Duration
+	verifyInterval          time.Duration
+	credentialCheckInterval time.Duration
+	pingCluster             func(ctx context.Context, connectionURL, username, password string) error
 }
 
-func (c *mongoDBAtlasConnectionProducer) secretValues() map[string]string {
-	return map[string]string{
-		c.PrivateKey: "[private_key]",
+// fresh returns an empty state that keeps the plugin type, logger and test
+// overrides of the current one. The verified passwords are kept too, so
+// rotations after reinitializing can still be rolled back, and so are the
+// project locks, so access list changes in flight stay serialized.
+func (s *connectionState) fresh() *connectionState {
+	s.mu.Lock()
+	rotatedPasswords := maps.Clone(s.rotatedPasswords)
+	projectLocks := s.sharedProjectLocks()
+	s.mu.Unlock()
+
+	return &connectionState{
+		Type:                    s.Type,
+		logger:                  s.logger,
+		baseURL:                 s.baseURL,
+		deploymentPollInterval:  s.deploymentPollInterval,
+		verifyInterval:          s.verifyInterval,
+		credentialCheckInterval: s.credentialCheckInterval,
+		pingCluster:             s.pingCluster,
+		rotatedPasswords:        rotatedPasswords,
+		projectLocks:            projectLocks,
 	}
 }
 
+// state returns the current connection state. Operations use the state they
+// started with throughout, even if Initialize swaps in a new one meanwhile.
+func (m *mongoDBAtlasConnectionProducer) state() *connectionState {
+	m.Lock()
+	defer m.Unlock()
+	return m.connectionState
+}
+
+// secretValues returns the secrets the sanitizer middleware removes from
+// errors. Operations also remove them, and their request's passwords,
+// themselves before returning.
+func (m *mongoDBAtlasConnectionProducer) secretValues() map[string]string {
+	return m.state().secrets()
+}
+
 // Close terminates the database connection.
-func (c *mongoDBAtlasConnectionProducer) Close() error {
-	c.Lock()
-	defer c.Unlock()
+func (m *mongoDBAtlasConnectionProducer) Close() error {
+	c := m.state()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
 	c.client = nil
+	c.userCounts = nil
+	c.rotatedPasswords = nil
+	c.userProjectIDs = nil
+	c.credentialClients = nil
+	c.userCredentials = nil
 
 	return nil
 }
 
-func (c *mongoDBAtlasConnectionProducer) Connection(_ context.Context) (interface{}, error) {
-	// This is intentionally not grabbing the lock since the calling functions (e.g. CreateUser)
-	// are claiming it. (The locking patterns could be refactored to be more consistent/clear.)
+// forgetUser drops everything cached about a deleted user.
+func (c *mongoDBAtlasConnectionProducer) forgetUser(username string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
+	delete(c.rotatedPasswords, username)
+	delete(c.userProjectIDs, username)
+	delete(c.userCredentials, username)
+}
+
+// Connection returns the client for the default API key. It is built on first
+// use, once for all concurrent callers, and cached until the key changes.
+func (c *mongoDBAtlasConnectionProducer) Connection(ctx context.Context) (interface{}, error) {
 	if !c.Initialized {
 		return nil, connutil.ErrNotInitialized
 	}
 
-	if c.client != nil {
-		return c.client, nil
+	c.refreshCredentials()
+
+	c.mu.Lock()
+	client := c.client
+	c.mu.Unlock()
+	if client != nil {
+		return client, nil
+	}
+
+	// Callers stop waiting when their own request is cancelled, while the
+	// build goes on for the others
+	built := c.clientFlight.DoChan("", func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), clientBuildTimeout)
+		defer cancel()
+		return c.buildClient(ctx)
+	})
+	select {
+	case res := <-built:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
+}
 
-	transport := digest.NewTransport(c.PublicKey, c.PrivateKey)
-	cl, err := transport.Client()
+// buildClient creates the default client and resolves project_name with it.
+// The client is only cached if the API key wasn't reloaded meanwhile.
+func (c *mongoDBAtlasConnectionProducer) buildClient(ctx context.Context) (*mongodbatlas.Client, error) {
+	c.mu.Lock()
+	publicKey, privateKey := c.PublicKey, c.PrivateKey
+	secondaryPrivateKey := c.SecondaryPrivateKey
+	c.mu.Unlock()
+
+	var transport http.RoundTripper = digest.NewTransport(publicKey, privateKey)
+	var failover *failoverTransport
+	if c.SecondaryPublicKey != "" {
+		failover = newFailoverTransport(c.logger, publicKey, privateKey, c.SecondaryPublicKey, secondaryPrivateKey)
+		failover.onFailover = c.reportAPIKey
+		transport = failover
+	}
+
+	client, err := c.newClient(transport)
 	if err != nil {
 		return nil, err
 	}
 
-	client, err := mongodbatlas.New(cl)
+	if err := c.resolveProjectName(ctx, client); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	cached := c.PublicKey == publicKey && c.PrivateKey == privateKey && c.SecondaryPrivateKey == secondaryPrivateKey
+	if cached {
+		c.client = client
+		c.failover = failover
+	}
+	c.mu.Unlock()
+
+	// A new client starts out with the primary key
+	if cached {
+		c.reportAPIKey(c.activeAPIKey())
+	}
+	return client, nil
+}
+
+// newClient returns an Atlas client authenticating through the transport.
+func (c *mongoDBAtlasConnectionProducer) newClient(transport http.RoundTripper) (*mongodbatlas.Client, error) {
+	transport = &changeTrackingTransport{base: transport}
+	if c.limiter != nil {
+		transport = &limitTransport{base: transport, limiter: c.limiter}
+	}
+	cl := &http.Client{Transport: transport}
+
+	var opts []mongodbatlas.ClientOpt
+	if c.baseURL != "" {
+		opts = append(opts, mongodbatlas.SetBaseURL(c.baseURL))
+	}
+
+	client, err := mongodbatlas.New(cl, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -82,19 +288,85 @@ func (c *mongoDBAtlasConnectionProducer) Connection(_ context.Context) (interfac
 	}
 	client.UserAgent = useragent.PluginString(env, userAgentPluginName)
 
-	c.client = client
-
-	return c.client, nil
+	return client, nil
 }
 
 func (m *mongoDBAtlasConnectionProducer) Initialize(ctx context.Context, req dbplugin.InitializeRequest) error {
+	return m.initialize(ctx, req, nil)
+}
+
+// initialize validates the config in a fresh state, which is swapped in only
+// if it is valid, together with anything apply sets. Operations in flight
+// finish with the previous state and client first.
+func (m *mongoDBAtlasConnectionProducer) initialize(ctx context.Context, req dbplugin.InitializeRequest, apply func()) error {
+	m.Lock()
+	previous := m.connectionState
+	state := previous.fresh()
+	m.Unlock()
+
+	// Errors are redacted with the new config's secrets, which the sanitizer
+	// middleware doesn't know about unless it is swapped in
+	state.RawConfig = req.Config
+	if err := decodeConfig(req.Config, state); err != nil {
+		return state.redact(err)
+	}
+
+	candidate := &mongoDBAtlasConnectionProducer{connectionState: state}
+	if err := candidate.validate(); err != nil {
+		return state.redact(err)
+	}
+
+	// Requests in flight keep counting against the limit unless it changed
+	switch limit := state.MaxConcurrentRequestsPerProject; {
+	case limit == 0:
+	case previous.limiter != nil && previous.limiter.limit == limit:
+		state.limiter = previous.limiter
+	default:
+		state.limiter = newProjectLimiter(limit)
+	}
+
+	// Set initialized to true at this point since all fields are set,
+	// and the connection can be established at a later time.
+	state.Initialized = true
+
+	// Connecting resolves project_name
+	if req.VerifyConnection && (len(state.CustomerX509CAs) > 0 || state.ProjectName != "") {
+		client, err := candidate.Connection(ctx)
+		if err != nil {
+			return state.redact(err)
+		}
+		if len(state.CustomerX509CAs) > 0 {
+			if err := candidate.ensureCustomerX509(ctx, client.(*mongodbatlas.Client)); err != nil {
+				return state.redact(err)
+			}
+		}
+	}
+
 	m.Lock()
 	defer m.Unlock()
 
-	m.RawConfig = req.Config
+	m.connectionState = state
+	if apply != nil {
+		apply()
+	}
+	return nil
+}
 
-	err := mapstructure.WeakDecode(req.Config, m)
-	if err != nil {
+// validate checks a freshly decoded config and loads external credentials.
+func (m *mongoDBAtlasConnectionProducer) validate() error {
+	if err := m.externalCredentialsConfig.validate(m.PublicKey, m.PrivateKey); err != nil {
+		return err
+	}
+	if err := m.secondaryKeyConfig.validate(); err != nil {
+		return err
+	}
+	if err := m.credentialsConfig.validate(); err != nil {
+		return err
+	}
+	if err := m.sessionConfig.validate(); err != nil {
+		return err
+	}
+	if _, err := m.loadExternalCredentials(); err != nil {
 		return err
 	}
 
@@ -106,9 +378,77 @@ func (m *mongoDBAtlasConnectionProducer) Initialize(ctx context.Context, req dbp
 		return errors.New("private Key is not set")
 	}
 
-	// Set initialized to true at this point since all fields are set,
-	// and the connection can be established at a later time.
-	m.Initialized = true
+	if err := m.projectNameConfig.validate(m.ProjectID); err != nil {
+		return err
+	}
+
+	if len(m.CustomerX509CAs) > 0 {
+		if m.ProjectID == "" && m.ProjectName == "" {
+			return errors.New("project_id or project_name must be set when customer_x509_cas is configured")
+		}
+		if _, err := m.customerCAs(); err != nil {
+			return err
+		}
+	}
+
+	if err := m.subjectPolicy.validate(); err != nil {
+		return fmt.Errorf("invalid subject policy: %w", err)
+	}
+
+	if err := m.rolePolicy.validate(); err != nil {
+		return fmt.Errorf("invalid role policy: %w", err)
+	}
+
+	if err := m.quotaConfig.validate(); err != nil {
+		return err
+	}
+
+	if err := m.ownershipConfig.validate(); err != nil {
+		return err
+	}
+
+	if err := m.deploymentConfig.validate(); err != nil {
+		return err
+	}
+
+	if err := m.verifyConfig.validate(); err != nil {
+		return err
+	}
+
+	if err := m.rotationConfig.validate(m.verifyConfig); err != nil {
+		return err
+	}
+
+	if err := m.projectOverrideConfig.validate(); err != nil {
+		return err
+	}
+
+	if err := m.concurrencyConfig.validate(); err != nil {
+		return err
+	}
+
+	if err := m.timeoutConfig.validate(); err != nil {
+		return err
+	}
 
 	return nil
 }
+
+// decodeConfig weakly decodes the connection config into out. Durations may be
+// given as a number of seconds or as a string with a unit suffix, e.g. "30s".
+func decodeConfig(config map[string]interface{}, out interface{}) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		WeaklyTypedInput: true,
+		DecodeHook: func(from, to reflect.Type, data interface{}) (interface{}, error) {
+			if to != reflect.TypeOf(time.Duration(0)) {
+				return data, nil
+			}
+			return parseutil.ParseDurationSecond(data)
+		},
+		Result: out,
+	})
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(config)
+}
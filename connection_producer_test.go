@@ -0,0 +1,61 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"context"
+	"testing"
+
+	dbplugin "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInitialize_Reconfigure(t *testing.T) {
+	atlas := newFakeAtlas(t)
+	db := atlas.newTestDB(t, map[string]interface{}{
+		"max_users_per_project": 5,
+		"username_template":     "first-{{random 8}}",
+	})
+
+	_, err := db.getConnection(context.Background(), "")
+	require.NoError(t, err)
+	previous := db.connectionState
+
+	initialize := func(config map[string]interface{}) error {
+		_, err := db.Initialize(context.Background(), dbplugin.InitializeRequest{Config: config})
+		return err
+	}
+
+	// An invalid config leaves the previous state and template in use
+	err = initialize(map[string]interface{}{
+		"public_key":        "public",
+		"project_id":        "other",
+		"username_template": "second-{{random 8}}",
+	})
+	require.ErrorContains(t, err, "private Key is not set")
+	require.Same(t, previous, db.connectionState)
+	require.NotNil(t, db.client)
+
+	err = initialize(map[string]interface{}{
+		"public_key":        "rotated-public",
+		"private_key":       "rotated-private",
+		"project_id":        "other",
+		"username_template": "second-{{random 8}}",
+	})
+	require.NoError(t, err)
+	require.Nil(t, db.client)
+	require.Equal(t, "rotated-public", db.PublicKey)
+	require.Zero(t, db.MaxUsersPerProject)
+
+	// Test overrides carry over to the new state
+	require.Equal(t, previous.baseURL, db.baseURL)
+
+	resp, err := db.NewUser(context.Background(), dbplugin.NewUserRequest{
+		Statements: dbplugin.Statements{Commands: []string{testMongoDBAtlasRole}},
+		Password:   "password",
+	})
+	require.NoError(t, err)
+	require.Regexp(t, "^second-", resp.Username)
+	require.NotNil(t, atlas.user("other", resp.Username))
+}
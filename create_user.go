@@ -0,0 +1,94 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"context"
+	"fmt"
+
+	dbplugin "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	"go.mongodb.org/atlas/mongodbatlas"
+)
+
+// maxCreateAttempts bounds how often a conflicting username is regenerated.
+const maxCreateAttempts = 3
+
+// createUser creates the database user. If Vault retries a request that
+// already created it, or the username is taken, Atlas reports a conflict.
+// Password credentials get a freshly generated username, since a user with
+// the same name may belong to another lease. A client certificate's username
+// is its subject, so a conflicting user that the plugin manages and that has
+// the same roles is the one Vault asked for before and is adopted. It
+// reports whether an existing user was adopted.
+func (m *MongoDBAtlas) createUser(ctx context.Context, client *mongodbatlas.Client, projectID string, req dbplugin.NewUserRequest, user *mongodbatlas.DatabaseUser) (bool, error) {
+	for attempt := 1; ; attempt++ {
+		_, _, err := client.DatabaseUsers.Create(ctx, projectID, user)
+		if err == nil {
+			return false, nil
+		}
+		if !isAtlasConflict(err) {
+			return false, fmt.Errorf("error creating user %q: %w", user.Username, err)
+		}
+
+		if req.CredentialType == dbplugin.CredentialTypeClientCertificate {
+			return m.adoptUser(ctx, client, projectID, user, err)
+		}
+		if attempt == maxCreateAttempts {
+			return false, fmt.Errorf("user %q already exists, giving up after %d generated usernames: %w",
+				user.Username, maxCreateAttempts, err)
+		}
+
+		username, err := m.usernameProducer.Generate(req.UsernameConfig)
+		if err != nil {
+			return false, err
+		}
+		m.logger.Info("username already exists, retrying with a new one", "username", user.Username)
+		user.Username = username
+	}
+}
+
+// adoptUser takes over an existing client certificate user that conflicted
+// with the one being created, if canAdopt allows it.
+func (m *MongoDBAtlas) adoptUser(ctx context.Context, client *mongodbatlas.Client, projectID string, user *mongodbatlas.DatabaseUser, conflict error) (bool, error) {
+	existing, _, err := client.DatabaseUsers.Get(ctx, user.DatabaseName, projectID, user.Username)
+	if err != nil || !canAdopt(existing, user) {
+		return false, fmt.Errorf("user %q already exists and is not a Vault managed user with the same roles: %w",
+			user.Username, conflict)
+	}
+	m.logger.Info("adopting existing user after create conflict", "username", user.Username)
+
+	update := &mongodbatlas.DatabaseUser{
+		DatabaseName: user.DatabaseName,
+		X509Type:     user.X509Type,
+		Password:     user.Password,
+		Roles:        user.Roles,
+		Scopes:       user.Scopes,
+		Labels:       user.Labels,
+	}
+	_, _, err = client.DatabaseUsers.Update(ctx, projectID, user.Username, update)
+	if err != nil {
+		return false, fmt.Errorf("error adopting existing user %q: %w", user.Username, err)
+	}
+	return true, nil
+}
+
+// canAdopt reports whether an existing user can stand in for the requested
+// one: it must carry the plugin's ownership label, not be soft revoked or a
+// static role user, and have the same authentication type and roles.
+func canAdopt(existing, requested *mongodbatlas.DatabaseUser) bool {
+	if value, ok := labelValue(existing, labelManagedBy); !ok || value != labelManagedByValue {
+		return false
+	}
+	if isRevokedUser(existing) || isStaticRoleUser(existing) || existing.X509Type != requested.X509Type {
+		return false
+	}
+	return sameRoles(existing.Roles, requested.Roles)
+}
+
+// sameRoles compares two role lists, ignoring order.
+func sameRoles(a, b []mongodbatlas.Role) bool {
+	return sameKeys(a, b, func(r mongodbatlas.Role) string {
+		return r.DatabaseName + "\x00" + r.CollectionName + "\x00" + r.RoleName
+	})
+}
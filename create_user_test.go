@@ -0,0 +1,141 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"context"
+	"testing"
+
+	dbplugin "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	"github.com/hashicorp/vault/sdk/helper/template"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/atlas/mongodbatlas"
+)
+
+func TestNewUser_Conflict(t *testing.T) {
+	statement := `{"database_name": "$external", "x509Type": "MANAGED", "roles": [{"databaseName":"app","roleName":"readWrite"}]}`
+	roles := []mongodbatlas.Role{{DatabaseName: "app", RoleName: "readWrite"}}
+
+	newCertUser := func(db *MongoDBAtlas) (dbplugin.NewUserResponse, error) {
+		return db.NewUser(context.Background(), dbplugin.NewUserRequest{
+			UsernameConfig: dbplugin.UsernameMetadata{DisplayName: "token", RoleName: "app"},
+			Statements:     dbplugin.Statements{Commands: []string{statement}},
+			CredentialType: dbplugin.CredentialTypeClientCertificate,
+			Subject:        "CN=client",
+		})
+	}
+
+	t.Run("adopts managed certificate user", func(t *testing.T) {
+		atlas := newFakeAtlas(t)
+		db := atlas.newTestDB(t, nil)
+		atlas.putUser("project", &mongodbatlas.DatabaseUser{
+			Username:     "CN=client",
+			DatabaseName: "$external",
+			X509Type:     "MANAGED",
+			Roles:        roles,
+			Labels:       managedUserLabels("app"),
+		})
+
+		resp, err := newCertUser(db)
+		require.NoError(t, err)
+		require.Equal(t, "CN=client", resp.Username)
+	})
+
+	tests := map[string]*mongodbatlas.DatabaseUser{
+		"unmanaged user": {
+			Username:     "CN=client",
+			DatabaseName: "$external",
+			X509Type:     "MANAGED",
+			Roles:        roles,
+		},
+		"different roles": {
+			Username:     "CN=client",
+			DatabaseName: "$external",
+			X509Type:     "MANAGED",
+			Roles:        []mongodbatlas.Role{{DatabaseName: "admin", RoleName: "atlasAdmin"}},
+			Labels:       managedUserLabels("app"),
+		},
+		"revoked user": {
+			Username:     "CN=client",
+			DatabaseName: "$external",
+			X509Type:     "MANAGED",
+			Roles:        roles,
+			Labels:       append(managedUserLabels("app"), mongodbatlas.Label{Key: labelRevokedAt, Value: "2024-01-01T00:00:00Z"}),
+		},
+	}
+	for name, existing := range tests {
+		t.Run(name, func(t *testing.T) {
+			atlas := newFakeAtlas(t)
+			db := atlas.newTestDB(t, nil)
+			atlas.putUser("project", existing)
+
+			_, err := newCertUser(db)
+			require.ErrorContains(t, err, `user "CN=client" already exists`)
+			require.Equal(t, existing.Roles, atlas.user("project", "CN=client").Roles)
+		})
+	}
+
+	newUser := func(db *MongoDBAtlas) (dbplugin.NewUserResponse, error) {
+		return db.NewUser(context.Background(), dbplugin.NewUserRequest{
+			UsernameConfig: dbplugin.UsernameMetadata{DisplayName: "token", RoleName: "app"},
+			Statements:     dbplugin.Statements{Commands: []string{`{"roles": [{"databaseName":"app","roleName":"readWrite"}]}`}},
+			Password:       "new-password",
+		})
+	}
+
+	t.Run("never adopts password user", func(t *testing.T) {
+		// The same username may belong to another lease of the role, so it
+		// is never taken over even if it looks like the requested user
+		atlas := newFakeAtlas(t)
+		db := atlas.newTestDB(t, nil)
+		atlas.putUser("project", &mongodbatlas.DatabaseUser{
+			Username:     "v-taken",
+			DatabaseName: "admin",
+			Password:     "old-password",
+			Roles:        roles,
+			Labels:       managedUserLabels("app"),
+		})
+
+		var err error
+		db.usernameProducer, err = newUsernameSequence("v-taken", "v-token-app-second")
+		require.NoError(t, err)
+
+		resp, err := newUser(db)
+		require.NoError(t, err)
+		require.Equal(t, "v-token-app-second", resp.Username)
+		require.Equal(t, "old-password", atlas.user("project", "v-taken").Password)
+	})
+
+	t.Run("gives up on taken usernames", func(t *testing.T) {
+		atlas := newFakeAtlas(t)
+		db := atlas.newTestDB(t, map[string]interface{}{"username_template": "fixed-{{.RoleName}}"})
+		atlas.putUser("project", &mongodbatlas.DatabaseUser{Username: "fixed-app", DatabaseName: "admin", Password: "old-password", Roles: roles})
+
+		_, err := newUser(db)
+		require.ErrorContains(t, err, `user "fixed-app" already exists`)
+		require.Equal(t, "old-password", atlas.user("project", "fixed-app").Password)
+	})
+}
+
+func TestDeleteUser_AlreadyDeleted(t *testing.T) {
+	atlas := newFakeAtlas(t)
+	db := atlas.newTestDB(t, nil)
+
+	_, err := db.DeleteUser(context.Background(), dbplugin.DeleteUserRequest{Username: "v-missing"})
+	require.NoError(t, err)
+}
+
+// newUsernameSequence returns a username template producing the given
+// usernames in order.
+func newUsernameSequence(usernames ...string) (template.StringTemplate, error) {
+	next := 0
+	return template.NewTemplate(
+		template.Template("{{next}}"),
+		template.Function("next", func() string {
+			username := usernames[min(next, len(usernames)-1)]
+			next++
+			return username
+		}),
+	)
+}
@@ -0,0 +1,144 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/mongodb-forks/digest"
+	"go.mongodb.org/atlas/mongodbatlas"
+)
+
+// labelCredential records the credential set a user was created with, so
+// that it can be revoked and rotated with the same API key.
+const labelCredential = "vault-credential"
+
+// credentialSet is an additional Atlas API key pair that statements can
+// select by name, e.g. to use a separately audited key for privileged roles.
+type credentialSet struct {
+	PublicKey  string `json:"public_key" structs:"public_key" mapstructure:"public_key"`
+	PrivateKey string `json:"private_key" structs:"private_key" mapstructure:"private_key"`
+
+	// PrivateKeyFile is read like the connection's private_key_file, which
+	// keeps the key out of the config Vault returns on reads.
+	PrivateKeyFile string `json:"private_key_file" structs:"private_key_file" mapstructure:"private_key_file"`
+}
+
+type credentialsConfig struct {
+	Credentials map[string]credentialSet `json:"credentials" structs:"credentials" mapstructure:"credentials"`
+}
+
+func (c credentialsConfig) validate() error {
+	for name, set := range c.Credentials {
+		if name == "" {
+			return errors.New("credentials must be named")
+		}
+		if set.PrivateKey != "" && set.PrivateKeyFile != "" {
+			return fmt.Errorf("credentials %q can only set one of private_key and private_key_file", name)
+		}
+		if set.PublicKey == "" || (set.PrivateKey == "" && set.PrivateKeyFile == "") {
+			return fmt.Errorf("credentials %q must set public_key and private_key or private_key_file", name)
+		}
+	}
+	return nil
+}
+
+// loadCredentialFiles reads the private keys of credential sets from their
+// files, and drops the cached client of every set whose key changed. Once
+// the state is in use, c.mu must be held.
+func (c *mongoDBAtlasConnectionProducer) loadCredentialFiles() error {
+	for name, set := range c.Credentials {
+		if set.PrivateKeyFile == "" {
+			continue
+		}
+		privateKey, err := readSecretFile(fmt.Sprintf("credentials.%s.private_key_file", name), set.PrivateKeyFile)
+		if err != nil {
+			return err
+		}
+		if privateKey == set.PrivateKey {
+			continue
+		}
+		if _, ok := c.credentialClients[name]; ok {
+			c.logger.Info("API key of credential set changed, rebuilding its Atlas client", "credential", name)
+			delete(c.credentialClients, name)
+		}
+		set.PrivateKey = privateKey
+		c.Credentials[name] = set
+	}
+	return nil
+}
+
+// credentialConnection returns the client for a named credential set, or the
+// default client if name is empty. Clients are cached per credential set.
+func (c *mongoDBAtlasConnectionProducer) credentialConnection(ctx context.Context, name string) (*mongodbatlas.Client, error) {
+	// The default connection is always made first since it resolves the project
+	conn, err := c.Connection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return conn.(*mongodbatlas.Client), nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	set, ok := c.Credentials[name]
+	if !ok {
+		return nil, fmt.Errorf("credential %q is not configured", name)
+	}
+
+	if client, ok := c.credentialClients[name]; ok {
+		return client, nil
+	}
+	client, err := c.newClient(digest.NewTransport(set.PublicKey, set.PrivateKey))
+	if err != nil {
+		return nil, err
+	}
+	if c.credentialClients == nil {
+		c.credentialClients = make(map[string]*mongodbatlas.Client)
+	}
+	c.credentialClients[name] = client
+	return client, nil
+}
+
+// userCredential returns the credential set to manage an existing user with:
+// the one named in the statement, or else the one it was created with.
+func (c *mongoDBAtlasConnectionProducer) userCredential(username, statementCredential string) string {
+	if statementCredential != "" {
+		return statementCredential
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.userCredentials[username]
+}
+
+// rememberUserCredential caches the credential set a user was created with.
+func (c *mongoDBAtlasConnectionProducer) rememberUserCredential(username, name string) {
+	if name == "" {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.userCredentials == nil {
+		c.userCredentials = make(map[string]string)
+	}
+	c.userCredentials[username] = name
+}
+
+// userConnection returns the client for the credential set recorded on a
+// user, if it differs from the one the user was found with.
+func (c *mongoDBAtlasConnectionProducer) userConnection(ctx context.Context, client *mongodbatlas.Client, credential string, user *mongodbatlas.DatabaseUser) (*mongodbatlas.Client, error) {
+	name, _ := labelValue(user, labelCredential)
+	if name == credential {
+		return client, nil
+	}
+	c.rememberUserCredential(user.Username, name)
+	return c.credentialConnection(ctx, name)
+}
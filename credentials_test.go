@@ -0,0 +1,96 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	dbplugin "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewUser_Credential(t *testing.T) {
+	atlas := newFakeAtlas(t)
+	config := map[string]interface{}{
+		"credentials": map[string]interface{}{
+			"privileged": map[string]interface{}{
+				"public_key":  "privileged-public",
+				"private_key": "privileged-private",
+			},
+		},
+	}
+	db := atlas.newTestDB(t, config)
+
+	statement := `{"credential": "privileged", "roles": [{"databaseName":"admin","roleName":"atlasAdmin"}]}`
+	resp, err := db.NewUser(context.Background(), dbplugin.NewUserRequest{
+		Statements: dbplugin.Statements{Commands: []string{statement}},
+		Password:   "password",
+	})
+	require.NoError(t, err)
+	require.Contains(t, db.credentialClients, "privileged")
+	require.NotSame(t, db.client, db.credentialClients["privileged"])
+
+	value, ok := labelValue(atlas.user("project", resp.Username), labelCredential)
+	require.True(t, ok)
+	require.Equal(t, "privileged", value)
+
+	// A fresh instance revokes with the credential set recorded on the user
+	db = atlas.newTestDB(t, config)
+	_, err = db.DeleteUser(context.Background(), dbplugin.DeleteUserRequest{Username: resp.Username})
+	require.NoError(t, err)
+	require.Contains(t, db.credentialClients, "privileged")
+	require.Nil(t, atlas.user("project", resp.Username))
+
+	_, err = db.NewUser(context.Background(), dbplugin.NewUserRequest{
+		Statements: dbplugin.Statements{Commands: []string{`{"credential": "missing", "roles": [{"databaseName":"admin","roleName":"read"}]}`}},
+		Password:   "password",
+	})
+	require.ErrorContains(t, err, `credential "missing" is not configured`)
+
+	require.Equal(t, "[credentials.privileged.private_key]", db.secretValues()["privileged-private"])
+}
+
+func TestCredentialsConfig_Validate(t *testing.T) {
+	require.NoError(t, credentialsConfig{}.validate())
+	require.ErrorContains(t, credentialsConfig{
+		Credentials: map[string]credentialSet{"admin": {PublicKey: "public"}},
+	}.validate(), `credentials "admin" must set public_key and private_key or private_key_file`)
+	require.ErrorContains(t, credentialsConfig{
+		Credentials: map[string]credentialSet{"admin": {PublicKey: "public", PrivateKey: "private", PrivateKeyFile: "/key"}},
+	}.validate(), `credentials "admin" can only set one of private_key and private_key_file`)
+}
+
+func TestCredentials_PrivateKeyFile(t *testing.T) {
+	atlas := newFakeAtlas(t)
+	keyFile := filepath.Join(t.TempDir(), "privileged_private_key")
+	require.NoError(t, os.WriteFile(keyFile, []byte("first-privileged\n"), 0o600))
+
+	db := atlas.newTestDB(t, map[string]interface{}{
+		"credentials": map[string]interface{}{
+			"privileged": map[string]interface{}{
+				"public_key":       "privileged-public",
+				"private_key_file": keyFile,
+			},
+		},
+	})
+	db.credentialCheckInterval = time.Nanosecond
+	require.Equal(t, "first-privileged", db.Credentials["privileged"].PrivateKey)
+
+	first, err := db.getConnection(context.Background(), "privileged")
+	require.NoError(t, err)
+	same, err := db.getConnection(context.Background(), "privileged")
+	require.NoError(t, err)
+	require.Same(t, first, same)
+
+	// Replacing the key in the file rebuilds the credential set's client
+	require.NoError(t, os.WriteFile(keyFile, []byte("second-privileged"), 0o600))
+	second, err := db.getConnection(context.Background(), "privileged")
+	require.NoError(t, err)
+	require.NotSame(t, first, second)
+	require.Equal(t, "[credentials.privileged.private_key]", db.secretValues()["second-privileged"])
+}
@@ -0,0 +1,148 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/atlas/mongodbatlas"
+)
+
+const x509TypeCustomer = "CUSTOMER"
+
+// ensureCustomerX509 makes sure the project trusts a customer CA before
+// CUSTOMER X.509 users are created. If CAs are configured on the connection,
+// the project's settings are compared against them and any drift is corrected.
+// Otherwise the project must already have been configured out-of-band.
+func (c *mongoDBAtlasConnectionProducer) ensureCustomerX509(ctx context.Context, client *mongodbatlas.Client) error {
+	projectID := c.projectID()
+	current, _, err := client.X509AuthDBUsers.GetCurrentX509Conf(ctx, projectID)
+	if err != nil {
+		return fmt.Errorf("error reading customer X.509 configuration for project: %w", err)
+	}
+
+	if len(c.CustomerX509CAs) == 0 {
+		if strings.TrimSpace(current.Cas) == "" {
+			return errors.New("project is not configured for customer X.509 authentication: " +
+				"set customer_x509_cas on the connection or configure a CA for the project in Atlas")
+		}
+		return nil
+	}
+
+	desired, err := c.customerCAs()
+	if err != nil {
+		return err
+	}
+
+	// A project CA that can't be parsed is treated as drift so it gets replaced
+	actual, err := parseCustomerCAs([]string{current.Cas})
+	if err == nil && sameCertificates(desired, actual) {
+		return nil
+	}
+
+	c.logger.Warn("customer X.509 configuration drift detected, updating project",
+		"project_id", projectID,
+		"configured_cas", len(desired),
+		"project_cas", len(actual))
+
+	_, _, err = client.X509AuthDBUsers.SaveConfiguration(ctx, projectID, &mongodbatlas.CustomerX509{
+		Cas: encodeCertificates(desired),
+	})
+	if err != nil {
+		return fmt.Errorf("error updating customer X.509 configuration for project: %w", err)
+	}
+	return nil
+}
+
+// customerCAs parses the configured customer_x509_cas. At least one
+// certificate is required, so the project's CAs are never replaced by an
+// empty set.
+func (c *connectionState) customerCAs() ([]*x509.Certificate, error) {
+	certs, err := parseCustomerCAs(c.CustomerX509CAs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid customer_x509_cas: %w", err)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("invalid customer_x509_cas: no certificates found")
+	}
+	return certs, nil
+}
+
+// parseCustomerCAs parses every PEM encoded certificate found in the given
+// bundles. Each bundle may hold one or more certificates.
+func parseCustomerCAs(bundles []string) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for _, bundle := range bundles {
+		rest := []byte(bundle)
+		for {
+			var block *pem.Block
+			block, rest = pem.Decode(rest)
+			if block == nil {
+				break
+			}
+			if block.Type != "CERTIFICATE" {
+				return nil, fmt.Errorf("unexpected PEM block type %q", block.Type)
+			}
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			if !cert.IsCA {
+				return nil, fmt.Errorf("certificate %q is not a CA", cert.Subject)
+			}
+			certs = append(certs, cert)
+		}
+		if len(bytes.TrimSpace(rest)) > 0 {
+			return nil, errors.New("trailing data after PEM certificates")
+		}
+	}
+	return certs, nil
+}
+
+// sameCertificates reports whether both lists hold the same set of
+// certificates, ignoring order and duplicates.
+func sameCertificates(a, b []*x509.Certificate) bool {
+	fa, fb := certificateFingerprints(a), certificateFingerprints(b)
+	if len(fa) != len(fb) {
+		return false
+	}
+	for i := range fa {
+		if fa[i] != fb[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func certificateFingerprints(certs []*x509.Certificate) []string {
+	seen := make(map[string]struct{}, len(certs))
+	var fingerprints []string
+	for _, cert := range certs {
+		sum := sha256.Sum256(cert.Raw)
+		fp := string(sum[:])
+		if _, ok := seen[fp]; ok {
+			continue
+		}
+		seen[fp] = struct{}{}
+		fingerprints = append(fingerprints, fp)
+	}
+	sort.Strings(fingerprints)
+	return fingerprints
+}
+
+func encodeCertificates(certs []*x509.Certificate) string {
+	var buf bytes.Buffer
+	for _, cert := range certs {
+		_ = pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	}
+	return buf.String()
+}
@@ -0,0 +1,127 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	dbplugin "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCustomerX509_SyncOnInitialize(t *testing.T) {
+	atlas := newFakeAtlas(t)
+	ca := testCACertificate(t, "vault-intermediate")
+
+	db := new()
+	db.baseURL = atlas.URL + "/"
+	_, err := db.Initialize(context.Background(), dbplugin.InitializeRequest{
+		Config: map[string]interface{}{
+			"public_key":        "public",
+			"private_key":       "private",
+			"project_id":        "project",
+			"customer_x509_cas": ca,
+		},
+		VerifyConnection: true,
+	})
+	require.NoError(t, err)
+
+	projectCAs, err := parseCustomerCAs([]string{atlas.customerX509["project"]})
+	require.NoError(t, err)
+	configuredCAs, err := parseCustomerCAs([]string{ca})
+	require.NoError(t, err)
+	require.True(t, sameCertificates(projectCAs, configuredCAs))
+}
+
+func TestCustomerX509_DriftCorrectedBeforeCreate(t *testing.T) {
+	atlas := newFakeAtlas(t)
+	ca := testCACertificate(t, "vault-intermediate")
+	atlas.customerX509["project"] = testCACertificate(t, "someone-else")
+
+	db := atlas.newTestDB(t, map[string]interface{}{
+		"customer_x509_cas": []interface{}{ca},
+	})
+
+	_, err := db.NewUser(context.Background(), dbplugin.NewUserRequest{
+		CredentialType: dbplugin.CredentialTypeClientCertificate,
+		Subject:        "CN=client",
+		Statements: dbplugin.Statements{
+			Commands: []string{testMongoDBAtlasX509Role},
+		},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, atlas.user("project", "CN=client"))
+
+	projectCAs, err := parseCustomerCAs([]string{atlas.customerX509["project"]})
+	require.NoError(t, err)
+	require.Len(t, projectCAs, 1)
+	require.Equal(t, "vault-intermediate", projectCAs[0].Subject.CommonName)
+}
+
+func TestCustomerX509_RefuseUnconfiguredProject(t *testing.T) {
+	atlas := newFakeAtlas(t)
+	db := atlas.newTestDB(t, nil)
+
+	_, err := db.NewUser(context.Background(), dbplugin.NewUserRequest{
+		CredentialType: dbplugin.CredentialTypeClientCertificate,
+		Subject:        "CN=client",
+		Statements: dbplugin.Statements{
+			Commands: []string{testMongoDBAtlasX509Role},
+		},
+	})
+	require.ErrorContains(t, err, "not configured for customer X.509")
+	require.Nil(t, atlas.user("project", "CN=client"))
+}
+
+func TestCustomerX509_InvalidConfig(t *testing.T) {
+	tests := map[string]interface{}{
+		"not a certificate": "not a certificate",
+		"empty bundle":      "",
+		"whitespace bundle": " \n\t",
+	}
+	for name, cas := range tests {
+		t.Run(name, func(t *testing.T) {
+			db := new()
+			_, err := db.Initialize(context.Background(), dbplugin.InitializeRequest{
+				Config: map[string]interface{}{
+					"public_key":        "public",
+					"private_key":       "private",
+					"project_id":        "project",
+					"customer_x509_cas": cas,
+				},
+			})
+			require.ErrorContains(t, err, "invalid customer_x509_cas")
+		})
+	}
+}
+
+func testCACertificate(t testing.TB, commonName string) string {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
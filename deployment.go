@@ -0,0 +1,109 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/atlas/mongodbatlas"
+)
+
+const (
+	defaultDeploymentTimeout      = 2 * time.Minute
+	defaultDeploymentPollInterval = 2 * time.Second
+)
+
+// deploymentConfig controls whether user changes wait until Atlas has applied
+// them to the clusters before returning.
+type deploymentConfig struct {
+	WaitForDeployment  bool          `json:"wait_for_deployment" structs:"wait_for_deployment" mapstructure:"wait_for_deployment"`
+	DeploymentTimeout  time.Duration `json:"deployment_timeout" structs:"deployment_timeout" mapstructure:"deployment_timeout"`
+	DeploymentClusters []string      `json:"deployment_clusters" structs:"deployment_clusters" mapstructure:"deployment_clusters"`
+}
+
+func (d deploymentConfig) validate() error {
+	if d.DeploymentTimeout < 0 {
+		return errors.New("deployment_timeout must not be negative")
+	}
+	return nil
+}
+
+// waitForDeployment polls the change status of the clusters a user change
+// applies to until every one of them reports APPLIED. Clusters are taken from
+// the user's CLUSTER scopes, then from deployment_clusters, and otherwise
+// every cluster in the project is checked.
+func (c *mongoDBAtlasConnectionProducer) waitForDeployment(ctx context.Context, client *mongodbatlas.Client, projectID string, scopes []mongodbatlas.Scope) error {
+	if !c.WaitForDeployment {
+		return nil
+	}
+
+	clusters, err := c.deploymentClusters(ctx, client, projectID, scopes)
+	if err != nil {
+		return fmt.Errorf("unable to determine clusters to wait for: %w", err)
+	}
+
+	timeout := c.DeploymentTimeout
+	if timeout == 0 {
+		timeout = defaultDeploymentTimeout
+	}
+	interval := c.deploymentPollInterval
+	if interval == 0 {
+		interval = defaultDeploymentPollInterval
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for _, cluster := range clusters {
+		for {
+			status, _, err := client.Clusters.Status(ctx, projectID, cluster)
+			if err == nil && status.ChangeStatus == mongodbatlas.ChangeStatusApplied {
+				break
+			}
+			if err != nil && ctx.Err() == nil {
+				return fmt.Errorf("error checking deployment status of cluster %q: %w", cluster, err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("timed out after %s waiting for cluster %q to apply the change; "+
+					"the change was accepted by Atlas and may still take effect", timeout, cluster)
+			case <-ticker.C:
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *mongoDBAtlasConnectionProducer) deploymentClusters(ctx context.Context, client *mongodbatlas.Client, projectID string, scopes []mongodbatlas.Scope) ([]string, error) {
+	var clusters []string
+	for _, scope := range scopes {
+		if scope.Type == "CLUSTER" {
+			clusters = append(clusters, scope.Name)
+		}
+	}
+	if len(clusters) > 0 {
+		return clusters, nil
+	}
+
+	if len(c.DeploymentClusters) > 0 {
+		return c.DeploymentClusters, nil
+	}
+
+	results, _, err := client.Clusters.List(ctx, projectID, &mongodbatlas.ListOptions{ItemsPerPage: listPageSize})
+	if err != nil {
+		return nil, err
+	}
+	for _, cluster := range results {
+		clusters = append(clusters, cluster.Name)
+	}
+	return clusters, nil
+}
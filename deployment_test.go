@@ -0,0 +1,66 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	dbplugin "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitForDeployment(t *testing.T) {
+	atlas := newFakeAtlas(t)
+	atlas.addCluster("project", "vault-test-free-cluster")
+	atlas.addCluster("project", "unrelated")
+	atlas.deployPolls = 2
+
+	db := atlas.newTestDB(t, map[string]interface{}{
+		"wait_for_deployment": true,
+	})
+	db.deploymentPollInterval = time.Millisecond
+
+	resp, err := db.NewUser(context.Background(), dbplugin.NewUserRequest{
+		Statements: dbplugin.Statements{Commands: []string{testMongoDBAtlasRole}},
+		Password:   "password",
+	})
+	require.NoError(t, err)
+
+	// Only the scoped cluster is waited for
+	require.Equal(t, 0, atlas.clusters["project"]["vault-test-free-cluster"])
+	require.Equal(t, 2, atlas.clusters["project"]["unrelated"])
+
+	_, err = db.UpdateUser(context.Background(), dbplugin.UpdateUserRequest{
+		Username: resp.Username,
+		Password: &dbplugin.ChangePassword{NewPassword: "rotated"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 0, atlas.clusters["project"]["vault-test-free-cluster"])
+
+	_, err = db.DeleteUser(context.Background(), dbplugin.DeleteUserRequest{Username: resp.Username})
+	require.NoError(t, err)
+	require.Equal(t, 0, atlas.clusters["project"]["vault-test-free-cluster"])
+}
+
+func TestWaitForDeployment_TimeoutRollsBackCreation(t *testing.T) {
+	atlas := newFakeAtlas(t)
+	atlas.addCluster("project", "cluster0")
+	atlas.deployPolls = 1000
+
+	db := atlas.newTestDB(t, map[string]interface{}{
+		"wait_for_deployment": true,
+		"deployment_timeout":  "50ms",
+		"deployment_clusters": "cluster0",
+	})
+	db.deploymentPollInterval = time.Millisecond
+
+	_, err := db.NewUser(context.Background(), dbplugin.NewUserRequest{
+		Statements: dbplugin.Statements{Commands: []string{`{"roles": [{"databaseName":"app","roleName":"read"}]}`}},
+		Password:   "password",
+	})
+	require.ErrorContains(t, err, `waiting for cluster "cluster0" to apply the change`)
+	require.Empty(t, atlas.users["project"])
+}
@@ -0,0 +1,149 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// defaultCredentialCheckInterval is how often external credential sources
+// are read again for changes.
+const defaultCredentialCheckInterval = 10 * time.Second
+
+// externalCredentialsConfig reads the API key from outside the Vault config,
+// e.g. from files mounted by Kubernetes. The sources are checked for changes
+// as the connection is used, so externally rotated keys take effect without
+// reinitializing the plugin.
+type externalCredentialsConfig struct {
+	PrivateKeyFile string `json:"private_key_file" structs:"private_key_file" mapstructure:"private_key_file"`
+
+	// CredentialsEnv is the prefix of the environment variables holding the
+	// API key, read from <prefix>PUBLIC_KEY and <prefix>PRIVATE_KEY.
+	CredentialsEnv string `json:"credentials_env" structs:"credentials_env" mapstructure:"credentials_env"`
+}
+
+func (e externalCredentialsConfig) validate(publicKey, privateKey string) error {
+	if e.PrivateKeyFile != "" && e.CredentialsEnv != "" {
+		return errors.New("only one of private_key_file and credentials_env can be configured")
+	}
+	if e.PrivateKeyFile != "" && privateKey != "" {
+		return errors.New("only one of private_key and private_key_file can be configured")
+	}
+	if e.CredentialsEnv != "" && (publicKey != "" || privateKey != "") {
+		return errors.New("public_key and private_key can't be configured with credentials_env")
+	}
+	return nil
+}
+
+func (e externalCredentialsConfig) enabled() bool {
+	return e.PrivateKeyFile != "" || e.CredentialsEnv != ""
+}
+
+// externalSources reports whether any API key or password is read from
+// outside the config.
+func (c *connectionState) externalSources() bool {
+	if c.externalCredentialsConfig.enabled() || c.SecondaryPrivateKeyFile != "" || c.SessionAdminPasswordFile != "" {
+		return true
+	}
+	for _, set := range c.Credentials {
+		if set.PrivateKeyFile != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// readSecretFile reads a secret from the file an option names.
+func readSecretFile(option, path string) (string, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to read %s: %w", option, err)
+	}
+	secret := strings.TrimSpace(string(contents))
+	if secret == "" {
+		return "", fmt.Errorf("%s %q is empty", option, path)
+	}
+	return secret, nil
+}
+
+// loadExternalCredentials reads the API keys from the configured sources and
+// reports whether they changed. Once the state is in use, c.mu must be held.
+func (c *mongoDBAtlasConnectionProducer) loadExternalCredentials() (bool, error) {
+	publicKey, privateKey := c.PublicKey, c.PrivateKey
+	secondaryPrivateKey := c.SecondaryPrivateKey
+
+	if err := c.loadCredentialFiles(); err != nil {
+		return false, err
+	}
+	if c.SessionAdminPasswordFile != "" {
+		password, err := readSecretFile("session_admin_password_file", c.SessionAdminPasswordFile)
+		if err != nil {
+			return false, err
+		}
+		c.SessionAdminPassword = password
+	}
+
+	var err error
+	if c.SecondaryPrivateKeyFile != "" {
+		secondaryPrivateKey, err = readSecretFile("secondary_private_key_file", c.SecondaryPrivateKeyFile)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	switch {
+	case c.PrivateKeyFile != "":
+		privateKey, err = readSecretFile("private_key_file", c.PrivateKeyFile)
+		if err != nil {
+			return false, err
+		}
+	case c.CredentialsEnv != "":
+		publicKey = os.Getenv(c.CredentialsEnv + "PUBLIC_KEY")
+		privateKey = os.Getenv(c.CredentialsEnv + "PRIVATE_KEY")
+		if publicKey == "" || privateKey == "" {
+			return false, fmt.Errorf("environment variables %sPUBLIC_KEY and %sPRIVATE_KEY must be set",
+				c.CredentialsEnv, c.CredentialsEnv)
+		}
+	}
+
+	c.credentialsCheckedAt = time.Now()
+	changed := publicKey != c.PublicKey || privateKey != c.PrivateKey || secondaryPrivateKey != c.SecondaryPrivateKey
+	c.PublicKey, c.PrivateKey = publicKey, privateKey
+	c.SecondaryPrivateKey = secondaryPrivateKey
+	return changed, nil
+}
+
+// refreshCredentials drops the cached client if the external credentials
+// changed since they were last read. A source that can't be read, e.g. while
+// a mounted secret is being replaced, keeps the current key in use.
+func (c *mongoDBAtlasConnectionProducer) refreshCredentials() {
+	if !c.externalSources() {
+		return
+	}
+
+	interval := c.credentialCheckInterval
+	if interval == 0 {
+		interval = defaultCredentialCheckInterval
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.credentialsCheckedAt) < interval {
+		return
+	}
+
+	changed, err := c.loadExternalCredentials()
+	if err != nil {
+		c.logger.Warn("unable to reload API key, keeping the current one", "error", err)
+		return
+	}
+	if changed && c.client != nil {
+		c.logger.Info("API key changed, rebuilding the Atlas client")
+		c.client = nil
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	dbplugin "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnection_PrivateKeyFile(t *testing.T) {
+	atlas := newFakeAtlas(t)
+	keyFile := filepath.Join(t.TempDir(), "private_key")
+	require.NoError(t, os.WriteFile(keyFile, []byte("first-private\n"), 0o600))
+
+	db := new()
+	db.baseURL = atlas.URL + "/"
+	db.credentialCheckInterval = time.Nanosecond
+	_, err := db.Initialize(context.Background(), dbplugin.InitializeRequest{
+		Config: map[string]interface{}{
+			"public_key":       "public",
+			"private_key_file": keyFile,
+			"project_id":       "project",
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "first-private", db.PrivateKey)
+
+	first, err := db.getConnection(context.Background(), "")
+	require.NoError(t, err)
+	same, err := db.getConnection(context.Background(), "")
+	require.NoError(t, err)
+	require.Same(t, first, same)
+
+	require.NoError(t, os.WriteFile(keyFile, []byte("second-private"), 0o600))
+	second, err := db.getConnection(context.Background(), "")
+	require.NoError(t, err)
+	require.NotSame(t, first, second)
+	require.Equal(t, "second-private", db.PrivateKey)
+	require.Equal(t, "[private_key]", db.secretValues()["second-private"])
+
+	// The current key stays in use while the file can't be read
+	require.NoError(t, os.Remove(keyFile))
+	third, err := db.getConnection(context.Background(), "")
+	require.NoError(t, err)
+	require.Same(t, second, third)
+}
+
+func TestConnection_CredentialsEnv(t *testing.T) {
+	atlas := newFakeAtlas(t)
+	atlas.apiKeys = map[string]bool{"env-public": true}
+	t.Setenv("TEST_ATLAS_PUBLIC_KEY", "env-public")
+	t.Setenv("TEST_ATLAS_PRIVATE_KEY", "env-private")
+
+	db := atlas.newTestDB(t, map[string]interface{}{
+		"public_key":      "",
+		"private_key":     "",
+		"credentials_env": "TEST_ATLAS_",
+	})
+	db.credentialCheckInterval = time.Nanosecond
+
+	newUser := func() error {
+		_, err := db.NewUser(context.Background(), dbplugin.NewUserRequest{
+			Statements: dbplugin.Statements{Commands: []string{testMongoDBAtlasRole}},
+			Password:   "password",
+		})
+		return err
+	}
+	require.NoError(t, newUser())
+
+	atlas.Lock()
+	atlas.apiKeys = map[string]bool{"rotated-public": true}
+	atlas.Unlock()
+	require.Error(t, newUser())
+
+	t.Setenv("TEST_ATLAS_PUBLIC_KEY", "rotated-public")
+	require.NoError(t, newUser())
+}
+
+func TestExternalCredentialsConfig_Validate(t *testing.T) {
+	require.NoError(t, externalCredentialsConfig{}.validate("public", "private"))
+	require.ErrorContains(t, externalCredentialsConfig{PrivateKeyFile: "/key"}.validate("public", "private"),
+		"only one of private_key and private_key_file")
+	require.ErrorContains(t, externalCredentialsConfig{CredentialsEnv: "ATLAS_"}.validate("public", ""),
+		"can't be configured with credentials_env")
+	require.ErrorContains(t, externalCredentialsConfig{PrivateKeyFile: "/key", CredentialsEnv: "ATLAS_"}.validate("", ""),
+		"only one of private_key_file and credentials_env")
+}
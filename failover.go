@@ -0,0 +1,191 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/mongodb-forks/digest"
+)
+
+const (
+	apiKeyPrimary   = "primary"
+	apiKeySecondary = "secondary"
+)
+
+// secondaryKeyConfig is a second API key pair used when Atlas rejects the
+// active one, so keys can be rotated without downtime.
+type secondaryKeyConfig struct {
+	SecondaryPublicKey  string `json:"secondary_public_key" structs:"secondary_public_key" mapstructure:"secondary_public_key"`
+	SecondaryPrivateKey string `json:"secondary_private_key" structs:"secondary_private_key" mapstructure:"secondary_private_key"`
+
+	// SecondaryPrivateKeyFile is read like private_key_file, which keeps the
+	// key out of the config Vault returns on reads.
+	SecondaryPrivateKeyFile string `json:"secondary_private_key_file" structs:"secondary_private_key_file" mapstructure:"secondary_private_key_file"`
+
+	// APIKeyStatusFile is where the plugin reports which key is active, since
+	// Vault has no way to show plugin status on a connection.
+	APIKeyStatusFile string `json:"api_key_status_file" structs:"api_key_status_file" mapstructure:"api_key_status_file"`
+}
+
+// apiKeyStatus is written to api_key_status_file.
+type apiKeyStatus struct {
+	ActiveAPIKey string    `json:"active_api_key"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func (s secondaryKeyConfig) validate() error {
+	if s.SecondaryPrivateKey != "" && s.SecondaryPrivateKeyFile != "" {
+		return errors.New("only one of secondary_private_key and secondary_private_key_file can be configured")
+	}
+	if (s.SecondaryPublicKey == "") != (s.SecondaryPrivateKey == "" && s.SecondaryPrivateKeyFile == "") {
+		return errors.New("secondary_public_key and secondary_private_key must be set together")
+	}
+	return nil
+}
+
+// failoverTransport authenticates with the active key of a primary and a
+// secondary key pair. When Atlas rejects the active key with a 401, the
+// request is retried with the other key, which becomes active if it works.
+type failoverTransport struct {
+	logger hclog.Logger
+
+	// onFailover, if set, is called with the key that became active.
+	onFailover func(active string)
+
+	mu         sync.Mutex
+	transports map[string]http.RoundTripper
+	active     string
+}
+
+func newFailoverTransport(logger hclog.Logger, primaryPublic, primaryPrivate, secondaryPublic, secondaryPrivate string) *failoverTransport {
+	return &failoverTransport{
+		logger: logger,
+		transports: map[string]http.RoundTripper{
+			apiKeyPrimary:   digest.NewTransport(primaryPublic, primaryPrivate),
+			apiKeySecondary: digest.NewTransport(secondaryPublic, secondaryPrivate),
+		},
+		active: apiKeyPrimary,
+	}
+}
+
+// activeKey returns which key pair is currently used.
+func (t *failoverTransport) activeKey() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.active
+}
+
+func (t *failoverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// The body is needed again if the request is retried with the other key
+	if req.Body != nil && req.GetBody == nil {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+
+	active := t.activeKey()
+	resp, err := t.transports[active].RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	other := apiKeySecondary
+	if active == apiKeySecondary {
+		other = apiKeyPrimary
+	}
+
+	retry := req.Clone(req.Context())
+	if req.Body != nil {
+		retry.Body, err = req.GetBody()
+		if err != nil {
+			return resp, nil
+		}
+	}
+	retryResp, err := t.transports[other].RoundTrip(retry)
+	if err != nil || retryResp.StatusCode == http.StatusUnauthorized {
+		if retryResp != nil {
+			retryResp.Body.Close()
+		}
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	t.mu.Lock()
+	switched := t.active == active
+	if switched {
+		t.active = other
+		t.logger.Warn("Atlas rejected the active API key, failed over to the other key",
+			"rejected", active, "active", other)
+	}
+	t.mu.Unlock()
+
+	if switched && t.onFailover != nil {
+		t.onFailover(other)
+	}
+	return retryResp, nil
+}
+
+// activeAPIKey reports which of the primary and secondary API keys is in use.
+func (c *mongoDBAtlasConnectionProducer) activeAPIKey() string {
+	c.mu.Lock()
+	failover := c.failover
+	c.mu.Unlock()
+
+	if failover == nil {
+		return apiKeyPrimary
+	}
+	return failover.activeKey()
+}
+
+// reportAPIKey writes the active key to api_key_status_file, if configured.
+// The file is replaced atomically so readers never see a partial write.
+func (c *mongoDBAtlasConnectionProducer) reportAPIKey(active string) {
+	if c.APIKeyStatusFile == "" {
+		return
+	}
+
+	err := writeFileAtomic(c.APIKeyStatusFile, apiKeyStatus{ActiveAPIKey: active, UpdatedAt: time.Now().UTC()})
+	if err != nil {
+		c.logger.Warn("unable to write api_key_status_file", "error", err)
+	}
+}
+
+func writeFileAtomic(path string, value interface{}) error {
+	contents, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(contents, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
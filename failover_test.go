@@ -0,0 +1,110 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	dbplugin "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnection_SecondaryKeyFailover(t *testing.T) {
+	atlas := newFakeAtlas(t)
+	atlas.apiKeys = map[string]bool{"public": true, "secondary-public": true}
+	statusFile := filepath.Join(t.TempDir(), "api-key-status.json")
+	db := atlas.newTestDB(t, map[string]interface{}{
+		"secondary_public_key":  "secondary-public",
+		"secondary_private_key": "secondary-private",
+		"api_key_status_file":   statusFile,
+	})
+	var logs bytes.Buffer
+	db.logger = hclog.New(&hclog.LoggerOptions{Output: &logs})
+
+	newUser := func() error {
+		_, err := db.NewUser(context.Background(), dbplugin.NewUserRequest{
+			Statements: dbplugin.Statements{Commands: []string{testMongoDBAtlasRole}},
+			Password:   "password",
+		})
+		return err
+	}
+
+	activeInStatusFile := func() string {
+		contents, err := os.ReadFile(statusFile)
+		require.NoError(t, err)
+		var status apiKeyStatus
+		require.NoError(t, json.Unmarshal(contents, &status))
+		return status.ActiveAPIKey
+	}
+
+	require.NoError(t, newUser())
+	require.Equal(t, apiKeyPrimary, db.activeAPIKey())
+	require.Equal(t, apiKeyPrimary, activeInStatusFile())
+
+	// The primary key is revoked
+	atlas.Lock()
+	atlas.apiKeys = map[string]bool{"secondary-public": true}
+	atlas.Unlock()
+	require.NoError(t, newUser())
+	require.Equal(t, apiKeySecondary, db.activeAPIKey())
+	require.Equal(t, apiKeySecondary, activeInStatusFile())
+	require.Contains(t, logs.String(), "failed over to the other key: rejected=primary active=secondary")
+
+	// A new primary key is added back once the secondary is revoked
+	atlas.Lock()
+	atlas.apiKeys = map[string]bool{"public": true}
+	atlas.Unlock()
+	require.NoError(t, newUser())
+	require.Equal(t, apiKeyPrimary, db.activeAPIKey())
+
+	atlas.Lock()
+	atlas.apiKeys = map[string]bool{}
+	atlas.Unlock()
+	require.Error(t, newUser())
+
+	require.Equal(t, "[secondary_private_key]", db.secretValues()["secondary-private"])
+}
+
+func TestConnection_SecondaryPrivateKeyFile(t *testing.T) {
+	atlas := newFakeAtlas(t)
+	keyFile := filepath.Join(t.TempDir(), "secondary_private_key")
+	require.NoError(t, os.WriteFile(keyFile, []byte("first-secondary\n"), 0o600))
+
+	db := atlas.newTestDB(t, map[string]interface{}{
+		"secondary_public_key":       "secondary-public",
+		"secondary_private_key_file": keyFile,
+	})
+	db.credentialCheckInterval = time.Nanosecond
+	require.Equal(t, "first-secondary", db.SecondaryPrivateKey)
+
+	first, err := db.getConnection(context.Background(), "")
+	require.NoError(t, err)
+
+	// Replacing the key in the file rebuilds the client with it
+	require.NoError(t, os.WriteFile(keyFile, []byte("second-secondary"), 0o600))
+	second, err := db.getConnection(context.Background(), "")
+	require.NoError(t, err)
+	require.NotSame(t, first, second)
+	require.Equal(t, "second-secondary", db.SecondaryPrivateKey)
+	require.Equal(t, "[secondary_private_key]", db.secretValues()["second-secondary"])
+}
+
+func TestSecondaryKeyConfig_Validate(t *testing.T) {
+	require.NoError(t, secondaryKeyConfig{}.validate())
+	require.NoError(t, secondaryKeyConfig{SecondaryPublicKey: "public", SecondaryPrivateKeyFile: "/key"}.validate())
+	require.ErrorContains(t, secondaryKeyConfig{SecondaryPublicKey: "public"}.validate(),
+		"secondary_public_key and secondary_private_key must be set together")
+	require.ErrorContains(t, secondaryKeyConfig{
+		SecondaryPublicKey:      "public",
+		SecondaryPrivateKey:     "private",
+		SecondaryPrivateKeyFile: "/key",
+	}.validate(), "only one of secondary_private_key and secondary_private_key_file can be configured")
+}
@@ -0,0 +1,502 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	dbplugin "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	dbtesting "github.com/hashicorp/vault/sdk/database/dbplugin/v5/testing"
+	"go.mongodb.org/atlas/mongodbatlas"
+)
+
+// fakeAtlas is a minimal in-memory stand-in for the parts of the Atlas Admin
+// API that the plugin uses, so behavior can be tested without a real project.
+type fakeAtlas struct {
+	*httptest.Server
+
+	sync.Mutex
+	users        map[string]map[string]*mongodbatlas.DatabaseUser
+	customerX509 map[string]string
+	accessList   map[string]map[string]*mongodbatlas.ProjectIPAccessList
+	projects     map[string]*mongodbatlas.Project
+	requests     []string
+
+	// apiKeys holds the public keys Atlas accepts. When set, requests must
+	// authenticate with HTTP digest authentication.
+	apiKeys map[string]bool
+
+	// clusters maps each project's cluster names to the number of status
+	// polls left before a pending change is reported as APPLIED. Every user
+	// change resets it to deployPolls.
+	clusters    map[string]map[string]int
+	deployPolls int
+
+	// latency delays every response, and maxInFlight records the most
+	// requests that were being served at once.
+	latency     time.Duration
+	inFlight    int
+	maxInFlight int
+
+	// reject, when set, fails requests it returns a status and error code for.
+	reject func(r *http.Request) (int, string)
+}
+
+func newFakeAtlas(t testing.TB) *fakeAtlas {
+	t.Helper()
+
+	f := &fakeAtlas{
+		users:        make(map[string]map[string]*mongodbatlas.DatabaseUser),
+		customerX509: make(map[string]string),
+		accessList:   make(map[string]map[string]*mongodbatlas.ProjectIPAccessList),
+		clusters:     make(map[string]map[string]int),
+		projects:     make(map[string]*mongodbatlas.Project),
+	}
+
+	const base = "/api/atlas/v1.0/groups/{group}"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/atlas/v1.0/groups", f.listProjects)
+	mux.HandleFunc("GET /api/atlas/v1.0/orgs/{org}/groups", f.listOrgProjects)
+	mux.HandleFunc("GET /api/atlas/v1.0/groups/{group}", f.getProject)
+	// A literal byName segment would conflict with the per-group routes
+	mux.HandleFunc("GET /api/atlas/v1.0/groups/{group}/{name}", f.getProjectByName)
+	mux.HandleFunc("POST "+base+"/databaseUsers", f.createUser)
+	mux.HandleFunc("GET "+base+"/databaseUsers", f.listUsers)
+	mux.HandleFunc("GET "+base+"/databaseUsers/{db}/{username}", f.getUser)
+	mux.HandleFunc("PATCH "+base+"/databaseUsers/{db}/{username}", f.updateUser)
+	mux.HandleFunc("DELETE "+base+"/databaseUsers/{db}/{username}", f.deleteUser)
+	mux.HandleFunc("GET "+base+"/userSecurity", f.getUserSecurity)
+	mux.HandleFunc("PATCH "+base+"/userSecurity", f.updateUserSecurity)
+	mux.HandleFunc("GET "+base+"/clusters", f.listClusters)
+	mux.HandleFunc("GET "+base+"/clusters/{cluster}/status", f.clusterStatus)
+	mux.HandleFunc("POST "+base+"/accessList", f.createAccessList)
+	mux.HandleFunc("GET "+base+"/accessList/{entry}", f.getAccessList)
+	mux.HandleFunc("DELETE "+base+"/accessList/{entry}", f.deleteAccessList)
+
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.Lock()
+		if f.apiKeys != nil && !f.authenticated(w, r) {
+			f.Unlock()
+			return
+		}
+		f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+		w.Header().Set(atlasRequestIDHeader, "req-"+strconv.Itoa(len(f.requests)))
+		if f.reject != nil {
+			if status, code := f.reject(r); status != 0 {
+				f.Unlock()
+				writeAtlasError(w, status, code)
+				return
+			}
+		}
+		f.inFlight++
+		f.maxInFlight = max(f.maxInFlight, f.inFlight)
+		latency := f.latency
+		if parts := strings.Split(r.URL.Path, "/"); r.Method != http.MethodGet && len(parts) > 6 && parts[6] == "databaseUsers" {
+			for name := range f.clusters[parts[5]] {
+				f.clusters[parts[5]][name] = f.deployPolls
+			}
+		}
+		f.Unlock()
+
+		time.Sleep(latency)
+		mux.ServeHTTP(w, r)
+
+		f.Lock()
+		f.inFlight--
+		f.Unlock()
+	}))
+	t.Cleanup(f.Close)
+
+	return f
+}
+
+// authenticated performs the server side of digest authentication, only
+// checking the public key the client authenticates with.
+func (f *fakeAtlas) authenticated(w http.ResponseWriter, r *http.Request) bool {
+	auth := r.Header.Get("Authorization")
+	if auth == "" {
+		w.Header().Set("WWW-Authenticate", `Digest realm="MMS Public API", nonce="nonce", qop="auth", algorithm=MD5`)
+		writeAtlasError(w, http.StatusUnauthorized, "")
+		return false
+	}
+
+	for _, field := range strings.Split(strings.TrimPrefix(auth, "Digest "), ", ") {
+		if key, ok := strings.CutPrefix(field, "username="); ok && f.apiKeys[strings.Trim(key, `"`)] {
+			return true
+		}
+	}
+	writeAtlasError(w, http.StatusUnauthorized, "INVALID_API_KEY")
+	return false
+}
+
+// newTestDB returns an initialized plugin instance talking to the fake.
+func (f *fakeAtlas) newTestDB(t *testing.T, config map[string]interface{}) *MongoDBAtlas {
+	t.Helper()
+
+	db := new()
+	db.baseURL = f.URL + "/"
+
+	cfg := map[string]interface{}{
+		"public_key":  "public",
+		"private_key": "private-key",
+		"project_id":  "project",
+	}
+	for k, v := range config {
+		cfg[k] = v
+	}
+
+	dbtesting.AssertInitialize(t, db, dbplugin.InitializeRequest{Config: cfg})
+	t.Cleanup(func() { dbtesting.AssertClose(t, db) })
+
+	return db
+}
+
+func (f *fakeAtlas) user(project, username string) *mongodbatlas.DatabaseUser {
+	f.Lock()
+	defer f.Unlock()
+
+	return f.users[project][username]
+}
+
+func (f *fakeAtlas) putUser(project string, user *mongodbatlas.DatabaseUser) {
+	f.Lock()
+	defer f.Unlock()
+
+	if f.users[project] == nil {
+		f.users[project] = make(map[string]*mongodbatlas.DatabaseUser)
+	}
+	user.GroupID = project
+	f.users[project][user.Username] = user
+}
+
+func (f *fakeAtlas) createUser(w http.ResponseWriter, r *http.Request) {
+	var user mongodbatlas.DatabaseUser
+	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
+		writeAtlasError(w, http.StatusBadRequest, "INVALID_JSON")
+		return
+	}
+
+	project := r.PathValue("group")
+	if f.user(project, user.Username) != nil {
+		writeAtlasError(w, http.StatusConflict, "USER_ALREADY_EXISTS")
+		return
+	}
+	f.putUser(project, &user)
+
+	writeAtlasJSON(w, http.StatusCreated, user)
+}
+
+func (f *fakeAtlas) listUsers(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	var users []mongodbatlas.DatabaseUser
+	for _, u := range f.users[r.PathValue("group")] {
+		users = append(users, *u)
+	}
+	f.Unlock()
+
+	sort.Slice(users, func(i, j int) bool { return users[i].Username < users[j].Username })
+	total := len(users)
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("pageNum"))
+	perPage, _ := strconv.Atoi(r.URL.Query().Get("itemsPerPage"))
+	if page > 0 && perPage > 0 {
+		start := min((page-1)*perPage, total)
+		end := min(start+perPage, total)
+		users = users[start:end]
+	}
+
+	writeAtlasJSON(w, http.StatusOK, map[string]interface{}{
+		"results":    users,
+		"totalCount": total,
+	})
+}
+
+func (f *fakeAtlas) getUser(w http.ResponseWriter, r *http.Request) {
+	user := f.user(r.PathValue("group"), r.PathValue("username"))
+	if user == nil {
+		writeAtlasError(w, http.StatusNotFound, "USERNAME_NOT_FOUND")
+		return
+	}
+	writeAtlasJSON(w, http.StatusOK, user)
+}
+
+func (f *fakeAtlas) updateUser(w http.ResponseWriter, r *http.Request) {
+	user := f.user(r.PathValue("group"), r.PathValue("username"))
+	if user == nil {
+		writeAtlasError(w, http.StatusNotFound, "USERNAME_NOT_FOUND")
+		return
+	}
+
+	var update mongodbatlas.DatabaseUser
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		writeAtlasError(w, http.StatusBadRequest, "INVALID_JSON")
+		return
+	}
+
+	f.Lock()
+	if update.Password != "" {
+		user.Password = update.Password
+	}
+	if update.Roles != nil {
+		user.Roles = update.Roles
+	}
+	if update.Scopes != nil {
+		user.Scopes = update.Scopes
+	}
+	if update.Labels != nil {
+		user.Labels = update.Labels
+	}
+	if update.DeleteAfterDate != "" {
+		user.DeleteAfterDate = update.DeleteAfterDate
+	}
+	updated := *user
+	f.Unlock()
+
+	writeAtlasJSON(w, http.StatusOK, updated)
+}
+
+func (f *fakeAtlas) deleteUser(w http.ResponseWriter, r *http.Request) {
+	project, username := r.PathValue("group"), r.PathValue("username")
+	if f.user(project, username) == nil {
+		writeAtlasError(w, http.StatusNotFound, "USERNAME_NOT_FOUND")
+		return
+	}
+
+	f.Lock()
+	delete(f.users[project], username)
+	f.Unlock()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *fakeAtlas) getUserSecurity(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	cas := f.customerX509[r.PathValue("group")]
+	f.Unlock()
+
+	writeAtlasJSON(w, http.StatusOK, mongodbatlas.UserSecurity{
+		CustomerX509: mongodbatlas.CustomerX509{Cas: cas},
+	})
+}
+
+func (f *fakeAtlas) updateUserSecurity(w http.ResponseWriter, r *http.Request) {
+	var security mongodbatlas.UserSecurity
+	if err := json.NewDecoder(r.Body).Decode(&security); err != nil {
+		writeAtlasError(w, http.StatusBadRequest, "INVALID_JSON")
+		return
+	}
+
+	f.Lock()
+	f.customerX509[r.PathValue("group")] = security.CustomerX509.Cas
+	f.Unlock()
+
+	writeAtlasJSON(w, http.StatusOK, security)
+}
+
+func (f *fakeAtlas) addCluster(project, name string) {
+	f.Lock()
+	defer f.Unlock()
+
+	if f.clusters[project] == nil {
+		f.clusters[project] = make(map[string]int)
+	}
+	f.clusters[project][name] = 0
+}
+
+func (f *fakeAtlas) listClusters(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	var clusters []mongodbatlas.Cluster
+	for name := range f.clusters[r.PathValue("group")] {
+		clusters = append(clusters, mongodbatlas.Cluster{Name: name})
+	}
+	f.Unlock()
+
+	writeAtlasJSON(w, http.StatusOK, map[string]interface{}{
+		"results":    clusters,
+		"totalCount": len(clusters),
+	})
+}
+
+func (f *fakeAtlas) clusterStatus(w http.ResponseWriter, r *http.Request) {
+	project, cluster := r.PathValue("group"), r.PathValue("cluster")
+
+	f.Lock()
+	pending, ok := f.clusters[project][cluster]
+	if ok && pending > 0 {
+		f.clusters[project][cluster] = pending - 1
+	}
+	f.Unlock()
+
+	if !ok {
+		writeAtlasError(w, http.StatusNotFound, "CLUSTER_NOT_FOUND")
+		return
+	}
+
+	status := mongodbatlas.ChangeStatusApplied
+	if pending > 0 {
+		status = mongodbatlas.ChangeStatusPending
+	}
+	writeAtlasJSON(w, http.StatusOK, mongodbatlas.ClusterStatus{ChangeStatus: status})
+}
+
+func (f *fakeAtlas) accessListEntry(project, entry string) *mongodbatlas.ProjectIPAccessList {
+	f.Lock()
+	defer f.Unlock()
+
+	return f.accessList[project][entry]
+}
+
+func (f *fakeAtlas) putAccessListEntry(project string, entry *mongodbatlas.ProjectIPAccessList) {
+	f.Lock()
+	defer f.Unlock()
+
+	if f.accessList[project] == nil {
+		f.accessList[project] = make(map[string]*mongodbatlas.ProjectIPAccessList)
+	}
+	key := entry.CIDRBlock
+	if key == "" {
+		key = entry.IPAddress
+	}
+	entry.GroupID = project
+	f.accessList[project][key] = entry
+}
+
+func (f *fakeAtlas) createAccessList(w http.ResponseWriter, r *http.Request) {
+	var entries []*mongodbatlas.ProjectIPAccessList
+	if err := json.NewDecoder(r.Body).Decode(&entries); err != nil {
+		writeAtlasError(w, http.StatusBadRequest, "INVALID_JSON")
+		return
+	}
+
+	for _, entry := range entries {
+		f.putAccessListEntry(r.PathValue("group"), entry)
+	}
+
+	writeAtlasJSON(w, http.StatusCreated, mongodbatlas.ProjectIPAccessLists{TotalCount: len(entries)})
+}
+
+func (f *fakeAtlas) getAccessList(w http.ResponseWriter, r *http.Request) {
+	entry := f.accessListEntry(r.PathValue("group"), r.PathValue("entry"))
+	if entry == nil {
+		writeAtlasError(w, http.StatusNotFound, "ATLAS_NETWORK_PERMISSION_ENTRY_NOT_FOUND")
+		return
+	}
+	writeAtlasJSON(w, http.StatusOK, entry)
+}
+
+func (f *fakeAtlas) deleteAccessList(w http.ResponseWriter, r *http.Request) {
+	project, entry := r.PathValue("group"), r.PathValue("entry")
+	if f.accessListEntry(project, entry) == nil {
+		writeAtlasError(w, http.StatusNotFound, "ATLAS_NETWORK_PERMISSION_ENTRY_NOT_FOUND")
+		return
+	}
+
+	f.Lock()
+	delete(f.accessList[project], entry)
+	f.Unlock()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *fakeAtlas) addProject(id, name, orgID string) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.projects[id] = &mongodbatlas.Project{ID: id, Name: name, OrgID: orgID}
+}
+
+func (f *fakeAtlas) renameProject(id, name string) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.projects[id].Name = name
+}
+
+func (f *fakeAtlas) listProjects(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	var projects []*mongodbatlas.Project
+	for _, p := range f.projects {
+		projects = append(projects, p)
+	}
+	f.Unlock()
+
+	sort.Slice(projects, func(i, j int) bool { return projects[i].ID < projects[j].ID })
+	writeAtlasJSON(w, http.StatusOK, mongodbatlas.Projects{Results: projects, TotalCount: len(projects)})
+}
+
+func (f *fakeAtlas) listOrgProjects(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	var projects []*mongodbatlas.Project
+	for _, p := range f.projects {
+		// Atlas filters names by prefix rather than exact match
+		if p.OrgID == r.PathValue("org") && strings.HasPrefix(p.Name, r.URL.Query().Get("name")) {
+			projects = append(projects, p)
+		}
+	}
+	f.Unlock()
+
+	sort.Slice(projects, func(i, j int) bool { return projects[i].ID < projects[j].ID })
+	writeAtlasJSON(w, http.StatusOK, mongodbatlas.Projects{Results: projects, TotalCount: len(projects)})
+}
+
+func (f *fakeAtlas) getProject(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	project := f.projects[r.PathValue("group")]
+	f.Unlock()
+
+	if project == nil {
+		writeAtlasError(w, http.StatusNotFound, "GROUP_NOT_FOUND")
+		return
+	}
+	writeAtlasJSON(w, http.StatusOK, project)
+}
+
+func (f *fakeAtlas) getProjectByName(w http.ResponseWriter, r *http.Request) {
+	if r.PathValue("group") != "byName" {
+		http.NotFound(w, r)
+		return
+	}
+
+	f.Lock()
+	var project *mongodbatlas.Project
+	for _, p := range f.projects {
+		if p.Name == r.PathValue("name") {
+			project = p
+		}
+	}
+	f.Unlock()
+
+	if project == nil {
+		writeAtlasError(w, http.StatusNotFound, "GROUP_NAME_NOT_FOUND")
+		return
+	}
+	writeAtlasJSON(w, http.StatusOK, project)
+}
+
+func writeAtlasJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeAtlasError writes an Atlas error body. It isn't encoded from
+// mongodbatlas.ErrorResponse, whose untagged Response field would be sent
+// as null and clear the client's response when decoded.
+func writeAtlasError(w http.ResponseWriter, status int, code string) {
+	writeAtlasJSON(w, status, map[string]interface{}{
+		"error":     status,
+		"errorCode": code,
+		"reason":    http.StatusText(status),
+		"detail":    code,
+	})
+}
@@ -0,0 +1,134 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/atlas/mongodbatlas"
+)
+
+const (
+	// labelManagedBy marks database users created by this plugin.
+	labelManagedBy      = "managed-by"
+	labelManagedByValue = "vault-" + userAgentPluginName
+
+	// labelVaultRole records the Vault role a dynamic user was created for.
+	labelVaultRole = "vault-role"
+
+	listPageSize = 500
+)
+
+// managedUserLabels returns the labels attached to users created for the
+// given Vault role.
+func managedUserLabels(roleName string) []mongodbatlas.Label {
+	labels := []mongodbatlas.Label{
+		{Key: labelManagedBy, Value: labelManagedByValue},
+	}
+	if roleName != "" {
+		labels = append(labels, mongodbatlas.Label{Key: labelVaultRole, Value: roleName})
+	}
+	return labels
+}
+
+// labelValue returns the value of the label with the given key.
+func labelValue(user *mongodbatlas.DatabaseUser, key string) (string, bool) {
+	for _, label := range user.Labels {
+		if label.Key == key {
+			return label.Value, true
+		}
+	}
+	return "", false
+}
+
+// ownershipConfig controls how the plugin decides which database users it
+// manages, and whether it refuses to act on users it doesn't.
+type ownershipConfig struct {
+	ManagedUsernamePrefix string   `json:"managed_username_prefix" structs:"managed_username_prefix" mapstructure:"managed_username_prefix"`
+	ManagedUsernameRegex  string   `json:"managed_username_regex" structs:"managed_username_regex" mapstructure:"managed_username_regex"`
+	EnforceOwnership      bool     `json:"enforce_ownership" structs:"enforce_ownership" mapstructure:"enforce_ownership"`
+	StaticRoleUsernames   []string `json:"static_role_usernames" structs:"static_role_usernames" mapstructure:"static_role_usernames"`
+}
+
+func (o ownershipConfig) validate() error {
+	if o.ManagedUsernameRegex != "" {
+		if _, err := regexp.Compile(o.ManagedUsernameRegex); err != nil {
+			return fmt.Errorf("invalid managed_username_regex: %w", err)
+		}
+	}
+	for _, pattern := range o.StaticRoleUsernames {
+		if _, err := matchPattern(pattern, ""); err != nil {
+			return fmt.Errorf("invalid static_role_usernames: %w", err)
+		}
+	}
+	return nil
+}
+
+// isManagedUser reports whether the user was created by this plugin, either
+// because it carries the managed-by label or because its name matches the
+// configured managed username prefix or regex.
+func (c *mongoDBAtlasConnectionProducer) isManagedUser(user *mongodbatlas.DatabaseUser) bool {
+	if value, ok := labelValue(user, labelManagedBy); ok && value == labelManagedByValue {
+		return true
+	}
+	if c.ManagedUsernamePrefix != "" && strings.HasPrefix(user.Username, c.ManagedUsernamePrefix) {
+		return true
+	}
+	// The regex is validated during initialization
+	if c.ManagedUsernameRegex != "" {
+		matched, _ := regexp.MatchString(c.ManagedUsernameRegex, user.Username)
+		return matched
+	}
+	return false
+}
+
+// checkOwnership refuses to act on users the plugin does not manage when
+// enforce_ownership is set. Users matching static_role_usernames are exempt
+// when rotating passwords for static roles, and users onboarded as static
+// roles can only have their passwords rotated.
+func (c *mongoDBAtlasConnectionProducer) checkOwnership(user *mongodbatlas.DatabaseUser, operation string, staticRole bool) error {
+	if !c.EnforceOwnership {
+		return nil
+	}
+	if !staticRole && isStaticRoleUser(user) {
+		return fmt.Errorf("refusing to %s user %q: it is managed by a Vault static role", operation, user.Username)
+	}
+	if c.isManagedUser(user) {
+		return nil
+	}
+
+	if staticRole {
+		exempt, err := matchAny(c.StaticRoleUsernames, user.Username)
+		if err != nil {
+			return err
+		}
+		if exempt {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("refusing to %s user %q: it is not managed by Vault; it has no %q label "+
+		"and does not match the managed username prefix or regex", operation, user.Username, labelManagedBy)
+}
+
+// listUsers returns every database user in the project, following pagination.
+func listUsers(ctx context.Context, client *mongodbatlas.Client, projectID string) ([]mongodbatlas.DatabaseUser, error) {
+	var users []mongodbatlas.DatabaseUser
+	for page := 1; ; page++ {
+		results, _, err := client.DatabaseUsers.List(ctx, projectID, &mongodbatlas.ListOptions{
+			PageNum:      page,
+			ItemsPerPage: listPageSize,
+		})
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, results...)
+		if len(results) < listPageSize {
+			return users, nil
+		}
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"context"
+	"testing"
+
+	dbplugin "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/atlas/mongodbatlas"
+)
+
+func TestOwnership(t *testing.T) {
+	atlas := newFakeAtlas(t)
+	atlas.putUser("project", &mongodbatlas.DatabaseUser{Username: "human-dba", DatabaseName: "admin", Password: "original"})
+	atlas.putUser("project", &mongodbatlas.DatabaseUser{Username: "svc-reporting", DatabaseName: "admin", Password: "original"})
+	atlas.putUser("project", &mongodbatlas.DatabaseUser{Username: "legacy-app", DatabaseName: "admin"})
+	atlas.putUser("project", &mongodbatlas.DatabaseUser{Username: "v-labelled", DatabaseName: "admin", Labels: managedUserLabels("app")})
+
+	db := atlas.newTestDB(t, map[string]interface{}{
+		"enforce_ownership":      true,
+		"managed_username_regex": "^legacy-",
+		"static_role_usernames":  "svc-*",
+	})
+
+	rotate := func(username string) error {
+		_, err := db.UpdateUser(context.Background(), dbplugin.UpdateUserRequest{
+			Username: username,
+			Password: &dbplugin.ChangePassword{NewPassword: "rotated"},
+		})
+		return err
+	}
+	deleteUser := func(username string) error {
+		_, err := db.DeleteUser(context.Background(), dbplugin.DeleteUserRequest{Username: username})
+		return err
+	}
+
+	require.ErrorContains(t, rotate("human-dba"), `refusing to rotate the password of user "human-dba": it is not managed by Vault`)
+	require.Equal(t, "original", atlas.user("project", "human-dba").Password)
+	require.ErrorContains(t, deleteUser("human-dba"), `refusing to delete user "human-dba"`)
+	require.NotNil(t, atlas.user("project", "human-dba"))
+
+	// Static role exceptions only apply to rotation
+	require.NoError(t, rotate("svc-reporting"))
+	require.Equal(t, "rotated", atlas.user("project", "svc-reporting").Password)
+	require.ErrorContains(t, deleteUser("svc-reporting"), "it is managed by a Vault static role")
+
+	require.NoError(t, deleteUser("legacy-app"))
+	require.NoError(t, deleteUser("v-labelled"))
+}
@@ -6,9 +6,12 @@ package mongodbatlas
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/go-secure-stdlib/strutil"
 	dbplugin "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
 	"github.com/hashicorp/vault/sdk/database/helper/dbutil"
@@ -38,7 +41,10 @@ func New() (interface{}, error) {
 
 func new() *MongoDBAtlas {
 	connProducer := &mongoDBAtlasConnectionProducer{
-		Type: mongoDBAtlasTypeName,
+		connectionState: &connectionState{
+			Type:   mongoDBAtlasTypeName,
+			logger: hclog.New(&hclog.LoggerOptions{Name: userAgentPluginName}),
+		},
 	}
 
 	return &MongoDBAtlas{
@@ -59,16 +65,18 @@ func (m *MongoDBAtlas) Initialize(ctx context.Context, req dbplugin.InitializeRe
 	if err != nil {
 		return dbplugin.InitializeResponse{}, fmt.Errorf("unable to initialize username template: %w", err)
 	}
-	m.usernameProducer = up
 
-	_, err = m.usernameProducer.Generate(dbplugin.UsernameMetadata{})
+	_, err = up.Generate(dbplugin.UsernameMetadata{})
 	if err != nil {
 		return dbplugin.InitializeResponse{}, fmt.Errorf("invalid username template: %w", err)
 	}
 
-	err = m.mongoDBAtlasConnectionProducer.Initialize(ctx, req)
+	// The template is swapped in together with the rest of the new config
+	err = m.mongoDBAtlasConnectionProducer.initialize(ctx, req, func() {
+		m.usernameProducer = up
+	})
 	if err != nil {
-		return dbplugin.InitializeResponse{}, fmt.Errorf("failed to initialize: %w", err)
+		return dbplugin.InitializeResponse{}, fmt.Errorf("failed to initialize: %w", classifyAtlasError(err))
 	}
 
 	resp := dbplugin.InitializeResponse{
@@ -81,11 +89,31 @@ func (m *MongoDBAtlas) Initialize(ctx context.Context, req dbplugin.InitializeRe
 	return resp, nil
 }
 
-func (m *MongoDBAtlas) NewUser(ctx context.Context, req dbplugin.NewUserRequest) (dbplugin.NewUserResponse, error) {
-	// Grab the lock
+// snapshot returns a view of the plugin bound to the current connection
+// state and username template, for an operation to use throughout.
+func (m *MongoDBAtlas) snapshot() *MongoDBAtlas {
 	m.Lock()
 	defer m.Unlock()
 
+	return &MongoDBAtlas{
+		mongoDBAtlasConnectionProducer: &mongoDBAtlasConnectionProducer{connectionState: m.connectionState},
+		usernameProducer:               m.usernameProducer,
+	}
+}
+
+func (m *MongoDBAtlas) NewUser(ctx context.Context, req dbplugin.NewUserRequest) (dbplugin.NewUserResponse, error) {
+	m = m.snapshot()
+
+	ctx, cancel, changes := operationContext(ctx, m.CreateTimeout)
+	defer cancel()
+
+	resp, err := m.newUser(ctx, req)
+	err = operationError(ctx, "creating the user", changes, classifyAtlasError(err))
+	return resp, m.redact(err, req.Password)
+}
+
+func (m *MongoDBAtlas) newUser(ctx context.Context, req dbplugin.NewUserRequest) (dbplugin.NewUserResponse, error) {
+
 	// Statement length checks
 	if len(req.Statements.Commands) == 0 {
 		return dbplugin.NewUserResponse{}, dbutil.ErrEmptyCreationStatement
@@ -94,7 +122,31 @@ func (m *MongoDBAtlas) NewUser(ctx context.Context, req dbplugin.NewUserRequest)
 		return dbplugin.NewUserResponse{}, fmt.Errorf("only 1 creation statement supported for creation")
 	}
 
-	client, err := m.getConnection(ctx)
+	// Unmarshal creation statements into mongodb roles
+	var databaseUser mongoDBAtlasStatement
+	err := json.Unmarshal([]byte(req.Statements.Commands[0]), &databaseUser)
+	if err != nil {
+		return dbplugin.NewUserResponse{}, fmt.Errorf("error unmarshalling statement %s", err)
+	}
+
+	// Default to "admin" if no db provided
+	if databaseUser.DatabaseName == "" {
+		databaseUser.DatabaseName = "admin"
+	}
+
+	if len(databaseUser.Roles) == 0 {
+		return dbplugin.NewUserResponse{}, fmt.Errorf("roles array is required in creation statement")
+	}
+
+	if err := m.rolePolicy.check(databaseUser.Roles, databaseUser.Scopes); err != nil {
+		return dbplugin.NewUserResponse{}, err
+	}
+
+	if err := validateStatementLabels(databaseUser.Labels); err != nil {
+		return dbplugin.NewUserResponse{}, fmt.Errorf("invalid creation statement: %w", err)
+	}
+
+	accessList, err := renderAccessList(databaseUser.AccessList, req.UsernameConfig)
 	if err != nil {
 		return dbplugin.NewUserResponse{}, err
 	}
@@ -107,6 +159,13 @@ func (m *MongoDBAtlas) NewUser(ctx context.Context, req dbplugin.NewUserRequest)
 			return dbplugin.NewUserResponse{}, err
 		}
 	case dbplugin.CredentialTypeClientCertificate:
+		if err := databaseUser.subjectPolicy.validate(); err != nil {
+			return dbplugin.NewUserResponse{}, fmt.Errorf("invalid subject policy in creation statement: %w", err)
+		}
+		if err := checkSubject(req.Subject, m.subjectPolicy, databaseUser.subjectPolicy); err != nil {
+			return dbplugin.NewUserResponse{}, err
+		}
+
 		// MongoDb Atlas expects the username to equal the client certificate subject
 		// https://www.mongodb.com/docs/manual/tutorial/configure-x509-client-authentication/
 		username = req.Subject
@@ -115,20 +174,60 @@ func (m *MongoDBAtlas) NewUser(ctx context.Context, req dbplugin.NewUserRequest)
 			req.CredentialType)
 	}
 
-	// Unmarshal creation statements into mongodb roles
-	var databaseUser mongoDBAtlasStatement
-	err = json.Unmarshal([]byte(req.Statements.Commands[0]), &databaseUser)
+	client, err := m.getConnection(ctx, databaseUser.Credential)
 	if err != nil {
-		return dbplugin.NewUserResponse{}, fmt.Errorf("error unmarshalling statement %s", err)
+		return dbplugin.NewUserResponse{}, err
 	}
 
-	// Default to "admin" if no db provided
-	if databaseUser.DatabaseName == "" {
-		databaseUser.DatabaseName = "admin"
+	projectID, err := m.resolveProject(ctx, client, databaseUser)
+	if err != nil {
+		return dbplugin.NewUserResponse{}, err
 	}
 
-	if len(databaseUser.Roles) == 0 {
-		return dbplugin.NewUserResponse{}, fmt.Errorf("roles array is required in creation statement")
+	if err := m.checkProjects(ctx, client, projectID, databaseUser); err != nil {
+		return dbplugin.NewUserResponse{}, fmt.Errorf("invalid creation statement: %w", err)
+	}
+
+	// Customer X.509 users can only authenticate if the project trusts the issuing CA
+	if databaseUser.X509Type == x509TypeCustomer {
+		if projectID != m.projectID() {
+			return dbplugin.NewUserResponse{}, errors.New("customer X.509 users can only be created in the configured project")
+		}
+		if err := m.ensureCustomerX509(ctx, client); err != nil {
+			return dbplugin.NewUserResponse{}, err
+		}
+	}
+
+	// Quota reservations are released unless the users get created
+	var reservations []func()
+	created := false
+	defer func() {
+		if !created {
+			for _, release := range reservations {
+				release()
+			}
+		}
+	}()
+	release, err := m.checkQuota(ctx, client, projectID, req.UsernameConfig.RoleName)
+	if err != nil {
+		return dbplugin.NewUserResponse{}, err
+	}
+	reservations = append(reservations, release)
+	for _, project := range databaseUser.Projects {
+		release, err := m.checkQuota(ctx, client, project.ProjectID, req.UsernameConfig.RoleName)
+		if err != nil {
+			return dbplugin.NewUserResponse{}, err
+		}
+		reservations = append(reservations, release)
+	}
+
+	labels := append(managedUserLabels(req.UsernameConfig.RoleName), accessListLabels(accessList)...)
+	labels = append(labels, projectLabels(databaseUser.Projects)...)
+	if databaseUser.Credential != "" {
+		labels = append(labels, mongodbatlas.Label{Key: labelCredential, Value: databaseUser.Credential})
+	}
+	if projectID != m.projectID() {
+		labels = append(labels, mongodbatlas.Label{Key: labelHomeProject, Value: projectID})
 	}
 
 	databaseUserRequest := &mongodbatlas.DatabaseUser{
@@ -138,13 +237,60 @@ func (m *MongoDBAtlas) NewUser(ctx context.Context, req dbplugin.NewUserRequest)
 		Roles:        databaseUser.Roles,
 		Scopes:       databaseUser.Scopes,
 		X509Type:     databaseUser.X509Type,
+		Labels:       append(labels, databaseUser.Labels...),
 	}
 
-	_, _, err = client.DatabaseUsers.Create(ctx, m.ProjectID, databaseUserRequest)
+	adopted, err := m.createUser(ctx, client, projectID, req, databaseUserRequest)
 	if err != nil {
 		return dbplugin.NewUserResponse{}, err
 	}
+	username = databaseUserRequest.Username
+	if adopted {
+		release()
+	}
+	m.rememberUserProject(username, projectID)
+	m.rememberUserCredential(username, databaseUser.Credential)
+
+	// The user is only usable from the configured addresses, so creation is
+	// rolled back if they can't be added to the access list
+	projectIDs := []string{projectID}
+	if err := m.addAccessList(ctx, client, projectID, accessList, req.Expiration); err != nil {
+		m.rollbackNewUser(ctx, client, databaseUser.DatabaseName, username, accessList, projectIDs)
+		return dbplugin.NewUserResponse{}, err
+	}
+
+	for _, project := range databaseUser.Projects {
+		err := m.createInProject(ctx, client, projectID, databaseUserRequest, project, req.UsernameConfig.RoleName, accessList, req.Expiration)
+		if err != nil {
+			if !isAtlasConflict(err) {
+				projectIDs = append(projectIDs, project.ProjectID)
+			}
+			m.rollbackNewUser(ctx, client, databaseUser.DatabaseName, username, accessList, projectIDs)
+			return dbplugin.NewUserResponse{}, err
+		}
+		projectIDs = append(projectIDs, project.ProjectID)
+	}
+
+	if err := m.waitForDeployment(ctx, client, projectID, databaseUser.Scopes); err != nil {
+		m.rollbackNewUser(ctx, client, databaseUser.DatabaseName, username, accessList, projectIDs)
+		return dbplugin.NewUserResponse{}, err
+	}
+	for _, project := range databaseUser.Projects {
+		if err := m.waitForDeployment(ctx, client, project.ProjectID, project.Scopes); err != nil {
+			m.rollbackNewUser(ctx, client, databaseUser.DatabaseName, username, accessList, projectIDs)
+			return dbplugin.NewUserResponse{}, err
+		}
+	}
+
+	// Client certificate users have no password to verify with
+	if req.CredentialType == dbplugin.CredentialTypePassword {
+		if err := m.verifyCredentials(ctx, projectID, username, req.Password); err != nil {
+			m.rollbackNewUser(ctx, client, databaseUser.DatabaseName, username, accessList, projectIDs)
+			return dbplugin.NewUserResponse{}, err
+		}
+	}
 
+	created = true
 	resp := dbplugin.NewUserResponse{
 		Username: username,
 	}
@@ -152,21 +298,107 @@ func (m *MongoDBAtlas) NewUser(ctx context.Context, req dbplugin.NewUserRequest)
 	return resp, nil
 }
 
+// rollbackNewUser deletes a user whose creation could not be completed from
+// every project it was created in, along with any access list entries added
+// for it. Failures are logged since the original error is more useful to the
+// caller.
+func (m *MongoDBAtlas) rollbackNewUser(ctx context.Context, client *mongodbatlas.Client, databaseName, username string, accessList, projectIDs []string) {
+	ctx, cancel := cleanupContext(ctx)
+	defer cancel()
+
+	for _, projectID := range projectIDs {
+		_, err := client.DatabaseUsers.Delete(ctx, databaseName, projectID, username)
+		if err != nil && !isAtlasNotFound(err) {
+			m.logger.Error("failed to roll back user", "username", username, "project_id", projectID, "error", m.redact(err))
+		} else {
+			m.recordUserDeleted(projectID)
+		}
+
+		if err := m.releaseAccessList(ctx, client, projectID, username, accessList); err != nil {
+			m.logger.Error("failed to roll back access list entries", "username", username, "project_id", projectID, "error", m.redact(err))
+		}
+	}
+}
+
 func (m *MongoDBAtlas) UpdateUser(ctx context.Context, req dbplugin.UpdateUserRequest) (dbplugin.UpdateUserResponse, error) {
+	m = m.snapshot()
+
+	ctx, cancel, changes := operationContext(ctx, m.UpdateTimeout)
+	defer cancel()
+
 	if req.Password != nil {
-		err := m.changePassword(ctx, req.Username, req.Password.NewPassword)
-		return dbplugin.UpdateUserResponse{}, err
+		err := m.changePassword(ctx, req.Username, req.Password.NewPassword, req.Password.Statements.Commands)
+		err = operationError(ctx, "changing the password", changes, classifyAtlasError(err))
+		return dbplugin.UpdateUserResponse{}, m.redact(err, req.Password.NewPassword)
+	}
+
+	if req.Expiration != nil {
+		err := m.changeExpiration(ctx, req.Username, req.Expiration.NewExpiration)
+		err = operationError(ctx, "changing the expiration", changes, classifyAtlasError(err))
+		return dbplugin.UpdateUserResponse{}, m.redact(err)
 	}
 
-	// This also results in an no-op if the expiration is updated due to renewal.
 	return dbplugin.UpdateUserResponse{}, nil
 }
 
-func (m *MongoDBAtlas) changePassword(ctx context.Context, username, password string) error {
-	m.Lock()
-	defer m.Unlock()
+// changeExpiration extends the access list entries added for a user's lease
+// when the lease is renewed. The database user itself does not expire, so
+// there is nothing to do for users without access list entries, or users
+// that no longer exist.
+func (m *MongoDBAtlas) changeExpiration(ctx context.Context, username string, expiration time.Time) error {
+	credential := m.userCredential(username, "")
+	client, err := m.getConnection(ctx, credential)
+	if err != nil {
+		return err
+	}
 
-	client, err := m.getConnection(ctx)
+	projectID, user, err := m.findUser(ctx, client, authDatabase(username), username)
+	if errors.Is(err, errUserNotFound) {
+		m.logger.Warn("user to renew does not exist, nothing to extend", "username", username)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading user %q: %w", username, err)
+	}
+	accessList := userAccessList(user)
+	if len(accessList) == 0 {
+		return nil
+	}
+	client, err = m.userConnection(ctx, client, credential, user)
+	if err != nil {
+		return err
+	}
+
+	if err := m.addAccessList(ctx, client, projectID, accessList, expiration); err != nil {
+		return err
+	}
+	projectIDs, err := m.additionalProjects(ctx, client, user)
+	if err != nil {
+		return err
+	}
+	for _, projectID := range projectIDs {
+		if err := m.addAccessList(ctx, client, projectID, accessList, expiration); err != nil {
+			return fmt.Errorf("project %q: %w", projectID, err)
+		}
+	}
+	return nil
+}
+
+// changePassword rotates a user's password. Rotation statements, when set,
+// are applied in the same update so static roles converge on the roles,
+// scopes and labels they describe.
+func (m *MongoDBAtlas) changePassword(ctx context.Context, username, password string, statements []string) error {
+	statement, err := m.parseRotationStatement(statements)
+	if err != nil {
+		return err
+	}
+
+	var statementCredential string
+	if statement != nil {
+		statementCredential = statement.Credential
+	}
+	credential := m.userCredential(username, statementCredential)
+	client, err := m.getConnection(ctx, credential)
 	if err != nil {
 		return err
 	}
@@ -175,53 +407,161 @@ func (m *MongoDBAtlas) changePassword(ctx context.Context, username, password st
 		Password: password,
 	}
 
-	_, _, err = client.DatabaseUsers.Update(context.Background(), m.ProjectID, username, databaseUserRequest)
-	return err
-}
+	var projectID string
+	var user *mongodbatlas.DatabaseUser
+	if statement != nil && (statement.ProjectID != "" || statement.ProjectName != "") {
+		projectID, err = m.resolveProject(ctx, client, *statement)
+		if err != nil {
+			return err
+		}
+		user, _, err = client.DatabaseUsers.Get(ctx, "admin", projectID, username)
+	} else {
+		projectID, user, err = m.findUser(ctx, client, "admin", username)
+	}
+	if isAtlasNotFound(err) || errors.Is(err, errUserNotFound) {
+		where := fmt.Sprintf("project %q", m.projectID())
+		if projectID != "" {
+			where = fmt.Sprintf("project %q", projectID)
+		} else if len(m.AllowedProjects) > 0 {
+			where = "any allowed project"
+		}
+		return fmt.Errorf("user %q does not exist in %s; static roles can only manage existing users",
+			username, where)
+	}
+	if err != nil {
+		return fmt.Errorf("error reading user %q from project: %w", username, err)
+	}
+	if statementCredential == "" {
+		client, err = m.userConnection(ctx, client, credential, user)
+		if err != nil {
+			return err
+		}
+	}
+	if err := m.checkOwnership(user, "rotate the password of", true); err != nil {
+		return err
+	}
+	if err := m.onboardStaticRole(databaseUserRequest, user); err != nil {
+		return err
+	}
+	if statement != nil {
+		if drift := reconcileUser(databaseUserRequest, user, statement); len(drift) > 0 {
+			m.logger.Warn("correcting drift of static role user", "username", username,
+				"fields", strings.Join(drift, ","))
+		}
+	}
 
-func (m *MongoDBAtlas) DeleteUser(ctx context.Context, req dbplugin.DeleteUserRequest) (dbplugin.DeleteUserResponse, error) {
-	m.Lock()
-	defer m.Unlock()
+	m.logger.Debug("setting new password", "username", username)
+	user, _, err = client.DatabaseUsers.Update(ctx, projectID, username, databaseUserRequest)
+	if err != nil {
+		return fmt.Errorf("error updating user %q: %w", username, err)
+	}
 
-	client, err := m.getConnection(ctx)
+	// Copies of the user in additional projects share its password
+	projectIDs, err := m.additionalProjects(ctx, client, user)
 	if err != nil {
-		return dbplugin.DeleteUserResponse{}, err
+		return err
 	}
+	for _, projectID := range projectIDs {
+		_, _, err := client.DatabaseUsers.Update(ctx, projectID, username, &mongodbatlas.DatabaseUser{Password: password})
+		if err != nil {
+			return fmt.Errorf("error updating password in project %q: %w", projectID, err)
+		}
+	}
+
+	if err := m.waitForDeployment(ctx, client, projectID, user.Scopes); err != nil {
+		return err
+	}
+
+	return m.verifyRotation(ctx, client, append([]string{projectID}, projectIDs...), username, password)
+}
+
+func (m *MongoDBAtlas) DeleteUser(ctx context.Context, req dbplugin.DeleteUserRequest) (dbplugin.DeleteUserResponse, error) {
+	m = m.snapshot()
+
+	ctx, cancel, changes := operationContext(ctx, m.DeleteTimeout)
+	defer cancel()
+
+	resp, err := m.deleteUser(ctx, req)
+	err = operationError(ctx, "deleting the user", changes, classifyAtlasError(err))
+	return resp, m.redact(err)
+}
+
+func (m *MongoDBAtlas) deleteUser(ctx context.Context, req dbplugin.DeleteUserRequest) (dbplugin.DeleteUserResponse, error) {
 
 	var databaseUser mongoDBAtlasStatement
 	if len(req.Statements.Commands) > 0 {
-		err = json.Unmarshal([]byte(req.Statements.Commands[0]), &databaseUser)
+		err := json.Unmarshal([]byte(req.Statements.Commands[0]), &databaseUser)
 		if err != nil {
 			return dbplugin.DeleteUserResponse{}, fmt.Errorf("error unmarshalling statement %w", err)
 		}
 	}
 
-	// If the user is an X.509 user, delete the user from the $external database
-	if isX509User(req.Username) {
-		if databaseUser.DatabaseName == "" {
-			databaseUser.DatabaseName = "$external"
+	credential := m.userCredential(req.Username, databaseUser.Credential)
+	client, err := m.getConnection(ctx, credential)
+	if err != nil {
+		return dbplugin.DeleteUserResponse{}, err
+	}
+
+	if databaseUser.DatabaseName == "" {
+		databaseUser.DatabaseName = authDatabase(req.Username)
+	}
+
+	// A user that no longer exists counts as revoked, so Vault stops retrying
+	projectID, user, err := m.findUser(ctx, client, databaseUser.DatabaseName, req.Username)
+	if errors.Is(err, errUserNotFound) {
+		m.logger.Debug("user already deleted", "username", req.Username)
+		return dbplugin.DeleteUserResponse{}, nil
+	}
+	if err != nil {
+		return dbplugin.DeleteUserResponse{}, fmt.Errorf("error reading user from project: %w", err)
+	}
+	if databaseUser.Credential == "" {
+		client, err = m.userConnection(ctx, client, credential, user)
+		if err != nil {
+			return dbplugin.DeleteUserResponse{}, err
+		}
+	}
+
+	if err := m.checkOwnership(user, "delete", false); err != nil {
+		return dbplugin.DeleteUserResponse{}, err
+	}
+
+	if err := m.revokeInProjects(ctx, client, user, databaseUser); err != nil {
+		return dbplugin.DeleteUserResponse{}, err
+	}
+
+	if databaseUser.SoftRevoke {
+		err = m.softRevoke(ctx, client, projectID, user, databaseUser.RetainDays)
+		if err != nil {
+			return dbplugin.DeleteUserResponse{}, err
 		}
 	} else {
-		// If the user is not an X.509 user, delete the user from the MongoDB Atlas project
-		if databaseUser.DatabaseName == "" {
-			databaseUser.DatabaseName = "admin"
+		_, err = client.DatabaseUsers.Delete(ctx, databaseUser.DatabaseName, projectID, req.Username)
+		if err != nil && !isAtlasNotFound(err) {
+			return dbplugin.DeleteUserResponse{}, fmt.Errorf("error deleting user from project: %w", err)
 		}
 	}
+	m.recordUserDeleted(projectID)
+	m.forgetUser(req.Username)
 
-	_, err = client.DatabaseUsers.Delete(ctx, databaseUser.DatabaseName, m.ProjectID, req.Username)
+	err = m.releaseAccessList(ctx, client, projectID, req.Username, userAccessList(user))
 	if err != nil {
-		return dbplugin.DeleteUserResponse{}, fmt.Errorf("error deleting user from project: %w", err)
+		return dbplugin.DeleteUserResponse{}, err
 	}
-	return dbplugin.DeleteUserResponse{}, nil
-}
 
-func (m *MongoDBAtlas) getConnection(ctx context.Context) (*mongodbatlas.Client, error) {
-	client, err := m.Connection(ctx)
-	if err != nil {
-		return nil, err
+	if err := m.waitForDeployment(ctx, client, projectID, user.Scopes); err != nil {
+		return dbplugin.DeleteUserResponse{}, err
 	}
 
-	return client.(*mongodbatlas.Client), nil
+	m.terminateSessions(ctx, req.Username, databaseUser.DatabaseName)
+
+	return dbplugin.DeleteUserResponse{}, nil
+}
+
+// getConnection returns the client for the named credential set, or the
+// default client if credential is empty.
+func (m *MongoDBAtlas) getConnection(ctx context.Context, credential string) (*mongodbatlas.Client, error) {
+	return m.credentialConnection(ctx, credential)
 }
 
 // Type returns the TypeName for this backend
@@ -234,9 +574,32 @@ func isX509User(username string) bool {
 	return strings.HasPrefix(username, "CN=")
 }
 
+// authDatabase returns the default authentication database for a user.
+// X.509 users live in the $external database, all others in admin.
+func authDatabase(username string) string {
+	if isX509User(username) {
+		return "$external"
+	}
+	return "admin"
+}
+
 type mongoDBAtlasStatement struct {
 	DatabaseName string               `json:"database_name"`
 	Roles        []mongodbatlas.Role  `json:"roles,omitempty"`
 	Scopes       []mongodbatlas.Scope `json:"scopes,omitempty"`
 	X509Type     string               `json:"x509Type,omitempty"`
+	AccessList   []string             `json:"access_list,omitempty"`
+	Labels       []mongodbatlas.Label `json:"labels,omitempty"`
+	Projects     []projectStatement   `json:"projects,omitempty"`
+	Credential   string               `json:"credential,omitempty"`
+
+	// Project the user is created in, instead of the configured project
+	ProjectID   string `json:"project_id,omitempty"`
+	ProjectName string `json:"project_name,omitempty"`
+
+	// Revocation statement options
+	SoftRevoke bool `json:"soft_revoke,omitempty"`
+	RetainDays int  `json:"retain_days,omitempty"`
+
+	subjectPolicy
 }
@@ -0,0 +1,113 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/atlas/mongodbatlas"
+)
+
+// projectNameConfig identifies the project by name within an organization,
+// for projects whose IDs aren't known when the connection is configured.
+type projectNameConfig struct {
+	OrgID       string `json:"org_id" structs:"org_id" mapstructure:"org_id"`
+	ProjectName string `json:"project_name" structs:"project_name" mapstructure:"project_name"`
+}
+
+func (p projectNameConfig) validate(projectID string) error {
+	if p.ProjectName == "" {
+		if p.OrgID != "" {
+			return errors.New("project_name must be set when org_id is configured")
+		}
+		return nil
+	}
+	if projectID != "" {
+		return errors.New("only one of project_id and project_name can be configured")
+	}
+	if p.OrgID == "" {
+		return errors.New("org_id must be set when project_name is configured")
+	}
+	return nil
+}
+
+// resolveProjectName sets ProjectID from project_name. The ID is cached, and
+// each new connection checks that the project still has the configured name,
+// since a renamed project would otherwise keep being used silently.
+func (c *mongoDBAtlasConnectionProducer) resolveProjectName(ctx context.Context, client *mongodbatlas.Client) error {
+	if c.ProjectName == "" {
+		return nil
+	}
+
+	if c.resolvedProjectID != "" {
+		project, _, err := client.Projects.GetOneProject(ctx, c.resolvedProjectID)
+		switch {
+		case err == nil && project.Name == c.ProjectName:
+			return nil
+		case err == nil:
+			c.logger.Warn("configured project was renamed", "project_id", project.ID,
+				"project_name", c.ProjectName, "new_name", project.Name)
+			return fmt.Errorf("project %s was renamed from %q to %q; update project_name in the connection config",
+				project.ID, c.ProjectName, project.Name)
+		case !isAtlasNotFound(err):
+			return fmt.Errorf("error reading project %s: %w", c.resolvedProjectID, err)
+		}
+		c.logger.Warn("configured project no longer exists, resolving project_name again",
+			"project_id", c.resolvedProjectID, "project_name", c.ProjectName)
+	}
+
+	projectID, err := lookupProjectID(ctx, client, c.OrgID, c.ProjectName)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ProjectID = projectID
+	c.resolvedProjectID = projectID
+	return nil
+}
+
+// projectID returns the configured project, or the one project_name
+// resolved to.
+func (c *mongoDBAtlasConnectionProducer) projectID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ProjectID
+}
+
+// lookupProjectID returns the ID of the only project in the organization
+// with the given name.
+func lookupProjectID(ctx context.Context, client *mongodbatlas.Client, orgID, name string) (string, error) {
+	var ids []string
+	for page := 1; ; page++ {
+		projects, _, err := client.Organizations.Projects(ctx, orgID, &mongodbatlas.ProjectsListOptions{
+			Name:        name,
+			ListOptions: mongodbatlas.ListOptions{PageNum: page, ItemsPerPage: listPageSize},
+		})
+		if err != nil {
+			return "", fmt.Errorf("error listing projects of organization %q: %w", orgID, err)
+		}
+		// The name filter isn't guaranteed to be an exact match
+		for _, project := range projects.Results {
+			if project.Name == name {
+				ids = append(ids, project.ID)
+			}
+		}
+		if len(projects.Results) < listPageSize {
+			break
+		}
+	}
+
+	switch len(ids) {
+	case 0:
+		return "", fmt.Errorf("no project named %q in organization %q", name, orgID)
+	case 1:
+		return ids[0], nil
+	default:
+		return "", fmt.Errorf("%d projects are named %q in organization %q; set project_id instead", len(ids), name, orgID)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	dbplugin "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInitialize_ProjectName(t *testing.T) {
+	atlas := newFakeAtlas(t)
+	atlas.addProject("prod-id", "prod", "org")
+	atlas.addProject("prod-eu-id", "prod-eu", "org")
+	atlas.addProject("dup-1", "dup", "org")
+	atlas.addProject("dup-2", "dup", "org")
+	atlas.addProject("other-prod-id", "prod", "other-org")
+
+	initialize := func(config map[string]interface{}) (*MongoDBAtlas, error) {
+		db := new()
+		db.baseURL = atlas.URL + "/"
+		cfg := map[string]interface{}{
+			"public_key":  "public",
+			"private_key": "private",
+			"org_id":      "org",
+		}
+		for k, v := range config {
+			cfg[k] = v
+		}
+		_, err := db.Initialize(context.Background(), dbplugin.InitializeRequest{Config: cfg, VerifyConnection: true})
+		return db, err
+	}
+
+	db, err := initialize(map[string]interface{}{"project_name": "prod"})
+	require.NoError(t, err)
+	require.Equal(t, "prod-id", db.ProjectID)
+
+	resp, err := db.NewUser(context.Background(), dbplugin.NewUserRequest{
+		Statements: dbplugin.Statements{Commands: []string{testMongoDBAtlasRole}},
+		Password:   "password",
+	})
+	require.NoError(t, err)
+	require.NotNil(t, atlas.user("prod-id", resp.Username))
+
+	// Renames are detected when the next connection is made
+	atlas.renameProject("prod-id", "prod-old")
+	require.NoError(t, db.Close())
+	_, err = db.DeleteUser(context.Background(), dbplugin.DeleteUserRequest{Username: resp.Username})
+	require.ErrorContains(t, err, `project prod-id was renamed from "prod" to "prod-old"`)
+
+	_, err = initialize(map[string]interface{}{"project_name": "missing"})
+	require.ErrorContains(t, err, `no project named "missing" in organization "org"`)
+
+	_, err = initialize(map[string]interface{}{"project_name": "dup"})
+	require.ErrorContains(t, err, `2 projects are named "dup" in organization "org"; set project_id instead`)
+
+	_, err = initialize(map[string]interface{}{"project_name": "prod", "project_id": "prod-id"})
+	require.ErrorContains(t, err, "only one of project_id and project_name can be configured")
+
+	_, err = initialize(map[string]interface{}{"project_name": "prod", "org_id": ""})
+	require.ErrorContains(t, err, "org_id must be set when project_name is configured")
+}
+
+func TestConnection_ProjectNameFirstCallerCancelled(t *testing.T) {
+	atlas := newFakeAtlas(t)
+	atlas.addProject("prod-id", "prod", "org")
+	db := atlas.newTestDB(t, map[string]interface{}{
+		"project_id":   "",
+		"project_name": "prod",
+		"org_id":       "org",
+	})
+	atlas.latency = 50 * time.Millisecond
+
+	// The first caller gives up while project_name is resolved
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	first := make(chan error, 1)
+	go func() {
+		_, err := db.Connection(ctx)
+		first <- err
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	// Callers waiting on the same build still get the client
+	_, err := db.Connection(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "prod-id", db.projectID())
+	require.ErrorIs(t, <-first, context.DeadlineExceeded)
+}
@@ -0,0 +1,226 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/atlas/mongodbatlas"
+)
+
+// labelHomeProject names the project holding the user that Vault manages. It
+// is attached to users created outside the configured project, naming their
+// own project, and to copies of a user in additional projects, naming the
+// project holding the user that records them.
+const labelHomeProject = "vault-home-project"
+
+// errUserNotFound is returned when a user exists in none of the projects the
+// connection may manage.
+var errUserNotFound = errors.New("user not found in any allowed project")
+
+// projectOverrideConfig lets creation statements target projects other than
+// the configured one. Entries are project IDs or names, or globs of them.
+type projectOverrideConfig struct {
+	AllowedProjects []string `json:"allowed_projects" structs:"allowed_projects" mapstructure:"allowed_projects"`
+}
+
+func (p projectOverrideConfig) validate() error {
+	for _, pattern := range p.AllowedProjects {
+		if _, err := matchPattern(pattern, ""); err != nil {
+			return fmt.Errorf("invalid allowed_projects: %w", err)
+		}
+	}
+	return nil
+}
+
+// resolveProject returns the ID of the project a statement targets: its
+// project_id or project_name if set, otherwise the configured project.
+func (c *mongoDBAtlasConnectionProducer) resolveProject(ctx context.Context, client *mongodbatlas.Client, statement mongoDBAtlasStatement) (string, error) {
+	if statement.ProjectID != "" && statement.ProjectName != "" {
+		return "", errors.New("only one of project_id and project_name can be set in a statement")
+	}
+	if statement.ProjectID == "" && statement.ProjectName == "" {
+		projectID := c.projectID()
+		if projectID == "" {
+			return "", errors.New("project_id must be set in the connection or in the statement")
+		}
+		return projectID, nil
+	}
+	if len(c.AllowedProjects) == 0 {
+		return "", errors.New("statements can only set project_id or project_name when allowed_projects is configured")
+	}
+	return c.allowedProject(ctx, client, statement.ProjectID, statement.ProjectName)
+}
+
+// allowedProject looks up a project by ID, or by name if name is set, and
+// returns its ID if allowed_projects permits it.
+func (c *mongoDBAtlasConnectionProducer) allowedProject(ctx context.Context, client *mongodbatlas.Client, id, name string) (string, error) {
+	var project *mongodbatlas.Project
+	var err error
+	if name != "" {
+		project, _, err = client.Projects.GetOneProjectByName(ctx, name)
+	} else {
+		project, _, err = client.Projects.GetOneProject(ctx, id)
+	}
+	if isAtlasNotFound(err) {
+		return "", fmt.Errorf("project %q does not exist or is not accessible with the API key", id+name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("error reading project: %w", err)
+	}
+
+	allowed, err := c.projectAllowed(project)
+	if err != nil {
+		return "", err
+	}
+	if !allowed {
+		return "", fmt.Errorf("project %q (%s) is not in allowed_projects", project.Name, project.ID)
+	}
+	return project.ID, nil
+}
+
+// projectAllowed reports whether allowed_projects matches the project's ID or
+// name. The configured project is always allowed.
+func (c *mongoDBAtlasConnectionProducer) projectAllowed(project *mongodbatlas.Project) (bool, error) {
+	if project.ID == c.projectID() {
+		return true, nil
+	}
+	if ok, err := matchAny(c.AllowedProjects, project.ID); ok || err != nil {
+		return ok, err
+	}
+	return matchAny(c.AllowedProjects, project.Name)
+}
+
+// findUser returns the project holding an existing user and the user itself.
+// The project the user was last seen in and the configured project are tried
+// first. Only if neither holds the user are the allowed projects searched,
+// and there only users carrying a vault-home-project label, which the plugin
+// created, are considered. Copies of a user in additional projects lead back
+// to the user's own project.
+func (c *mongoDBAtlasConnectionProducer) findUser(ctx context.Context, client *mongodbatlas.Client, databaseName, username string) (string, *mongodbatlas.DatabaseUser, error) {
+	c.mu.Lock()
+	cachedProjectID := c.userProjectIDs[username]
+	c.mu.Unlock()
+
+	seen := make(map[string]struct{})
+	for _, projectID := range []string{cachedProjectID, c.projectID()} {
+		if _, ok := seen[projectID]; ok || projectID == "" {
+			continue
+		}
+		seen[projectID] = struct{}{}
+
+		projectID, user, err := c.lookupUser(ctx, client, databaseName, projectID, username, false)
+		if err != nil || user != nil {
+			return projectID, user, err
+		}
+	}
+	if len(c.AllowedProjects) == 0 {
+		return "", nil, errUserNotFound
+	}
+
+	for page := 1; ; page++ {
+		projects, _, err := client.Projects.GetAllProjects(ctx, &mongodbatlas.ListOptions{
+			PageNum:      page,
+			ItemsPerPage: listPageSize,
+		})
+		if err != nil {
+			return "", nil, fmt.Errorf("error listing projects: %w", err)
+		}
+		for _, project := range projects.Results {
+			if _, ok := seen[project.ID]; ok {
+				continue
+			}
+			allowed, err := c.projectAllowed(project)
+			if err != nil {
+				return "", nil, err
+			}
+			if !allowed {
+				continue
+			}
+
+			projectID, user, err := c.lookupUser(ctx, client, databaseName, project.ID, username, true)
+			if err != nil || user != nil {
+				return projectID, user, err
+			}
+		}
+		if len(projects.Results) < listPageSize {
+			return "", nil, errUserNotFound
+		}
+	}
+}
+
+// lookupUser reads a user from a project, following the home project label
+// of copies in additional projects if it names a project the connection may
+// manage. It returns no user if the project doesn't
+// hold it, or if labelled is set and the user has no home project label.
+func (c *mongoDBAtlasConnectionProducer) lookupUser(ctx context.Context, client *mongodbatlas.Client, databaseName, projectID, username string, labelled bool) (string, *mongodbatlas.DatabaseUser, error) {
+	user, _, err := client.DatabaseUsers.Get(ctx, databaseName, projectID, username)
+	if isAtlasNotFound(err) {
+		return "", nil, nil
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("error reading user from project %q: %w", projectID, err)
+	}
+
+	home, ok := labelValue(user, labelHomeProject)
+	if !ok && labelled {
+		return "", nil, nil
+	}
+	if ok && home != projectID {
+		allowed, err := c.labelledProjectAllowed(ctx, client, home)
+		if err != nil {
+			return "", nil, err
+		}
+		if !allowed {
+			c.logger.Warn("ignoring home project label naming a project outside allowed_projects",
+				"username", username, "project_id", projectID, "home_project_id", home)
+		} else {
+			homeUser, _, err := client.DatabaseUsers.Get(ctx, databaseName, home, username)
+			switch {
+			case err == nil:
+				projectID, user = home, homeUser
+			case !isAtlasNotFound(err):
+				return "", nil, fmt.Errorf("error reading user from project %q: %w", home, err)
+			}
+		}
+	}
+
+	c.rememberUserProject(username, projectID)
+	return projectID, user, nil
+}
+
+// labelledProjectAllowed reports whether a project named by a user's labels
+// may be managed. Labels can be edited in Atlas, so they are never trusted to
+// lead outside the configured and allowed projects.
+func (c *mongoDBAtlasConnectionProducer) labelledProjectAllowed(ctx context.Context, client *mongodbatlas.Client, projectID string) (bool, error) {
+	if projectID == c.projectID() {
+		return true, nil
+	}
+	if len(c.AllowedProjects) == 0 {
+		return false, nil
+	}
+
+	project, _, err := client.Projects.GetOneProject(ctx, projectID)
+	if isAtlasNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error reading project %q: %w", projectID, err)
+	}
+	return c.projectAllowed(project)
+}
+
+// rememberUserProject caches the project of a user so later operations on it
+// don't need to search the allowed projects.
+func (c *mongoDBAtlasConnectionProducer) rememberUserProject(username, projectID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.userProjectIDs == nil {
+		c.userProjectIDs = make(map[string]string)
+	}
+	c.userProjectIDs[username] = projectID
+}
@@ -0,0 +1,162 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	dbplugin "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/atlas/mongodbatlas"
+)
+
+func TestNewUser_ProjectOverride(t *testing.T) {
+	atlas := newFakeAtlas(t)
+	atlas.addProject("project", "default", "org")
+	atlas.addProject("analytics-id", "analytics", "org")
+	atlas.addProject("billing-id", "billing", "org")
+	db := atlas.newTestDB(t, map[string]interface{}{"allowed_projects": "analytics"})
+
+	newUser := func(statement string) (dbplugin.NewUserResponse, error) {
+		return db.NewUser(context.Background(), dbplugin.NewUserRequest{
+			UsernameConfig: dbplugin.UsernameMetadata{DisplayName: "token", RoleName: "app"},
+			Statements:     dbplugin.Statements{Commands: []string{statement}},
+			Password:       "password",
+		})
+	}
+
+	resp, err := newUser(`{"project_name": "analytics", "roles": [{"databaseName":"app","roleName":"read"}]}`)
+	require.NoError(t, err)
+	require.NotNil(t, atlas.user("analytics-id", resp.Username))
+	require.Nil(t, atlas.user("project", resp.Username))
+
+	_, err = newUser(`{"project_id": "billing-id", "roles": [{"databaseName":"app","roleName":"read"}]}`)
+	require.ErrorContains(t, err, `project "billing" (billing-id) is not in allowed_projects`)
+	_, err = newUser(`{"project_name": "missing", "roles": [{"databaseName":"app","roleName":"read"}]}`)
+	require.ErrorContains(t, err, `project "missing" does not exist`)
+
+	// A fresh plugin instance has to search the allowed projects for the user
+	db = atlas.newTestDB(t, map[string]interface{}{"allowed_projects": "analytics"})
+	_, err = db.UpdateUser(context.Background(), dbplugin.UpdateUserRequest{
+		Username: resp.Username,
+		Password: &dbplugin.ChangePassword{NewPassword: "rotated"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "rotated", atlas.user("analytics-id", resp.Username).Password)
+
+	_, err = db.DeleteUser(context.Background(), dbplugin.DeleteUserRequest{Username: resp.Username})
+	require.NoError(t, err)
+	require.Nil(t, atlas.user("analytics-id", resp.Username))
+}
+
+func TestNewUser_ProjectOverrideSkipsVerification(t *testing.T) {
+	atlas := newFakeAtlas(t)
+	atlas.addProject("project", "default", "org")
+	atlas.addProject("analytics-id", "analytics", "org")
+	db := atlas.newTestDB(t, map[string]interface{}{
+		"allowed_projects":      "analytics",
+		"verify_connection_url": "mongodb+srv://cluster0.example.mongodb.net",
+		"verify_timeout":        "20ms",
+		"two_phase_rotation":    true,
+	})
+	db.verifyInterval = time.Millisecond
+
+	// The cluster only knows users of the configured project
+	db.pingCluster = func(_ context.Context, _, username, _ string) error {
+		if atlas.user("project", username) == nil {
+			return errors.New("authentication failed")
+		}
+		return nil
+	}
+
+	resp, err := db.NewUser(context.Background(), dbplugin.NewUserRequest{
+		UsernameConfig: dbplugin.UsernameMetadata{DisplayName: "token", RoleName: "app"},
+		Statements:     dbplugin.Statements{Commands: []string{`{"project_name": "analytics", "roles": [{"databaseName":"app","roleName":"read"}]}`}},
+		Password:       "password",
+	})
+	require.NoError(t, err)
+	require.NotNil(t, atlas.user("analytics-id", resp.Username))
+
+	_, err = db.UpdateUser(context.Background(), dbplugin.UpdateUserRequest{
+		Username: resp.Username,
+		Password: &dbplugin.ChangePassword{NewPassword: "rotated"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "rotated", atlas.user("analytics-id", resp.Username).Password)
+}
+
+func TestFindUser_AllowedProjects(t *testing.T) {
+	atlas := newFakeAtlas(t)
+	atlas.addProject("project", "default", "org")
+	atlas.addProject("analytics-id", "analytics", "org")
+	atlas.putUser("project", &mongodbatlas.DatabaseUser{Username: "home", DatabaseName: "admin"})
+	atlas.putUser("analytics-id", &mongodbatlas.DatabaseUser{Username: "theirs", DatabaseName: "admin", Password: "theirs"})
+	db := atlas.newTestDB(t, map[string]interface{}{"allowed_projects": "analytics"})
+
+	client, err := db.getConnection(context.Background(), "")
+	require.NoError(t, err)
+
+	// Users in the configured project are found without listing projects
+	projectID, _, err := db.findUser(context.Background(), client, "admin", "home")
+	require.NoError(t, err)
+	require.Equal(t, "project", projectID)
+	require.NotContains(t, atlas.requests, "GET /api/atlas/v1.0/groups")
+
+	// A user of the same name the plugin didn't create in an allowed project
+	// is left alone
+	_, err = db.DeleteUser(context.Background(), dbplugin.DeleteUserRequest{Username: "theirs"})
+	require.NoError(t, err)
+	require.NotNil(t, atlas.user("analytics-id", "theirs"))
+	_, err = db.UpdateUser(context.Background(), dbplugin.UpdateUserRequest{
+		Username: "theirs",
+		Password: &dbplugin.ChangePassword{NewPassword: "rotated"},
+	})
+	require.Error(t, err)
+	require.Equal(t, "theirs", atlas.user("analytics-id", "theirs").Password)
+
+	// A home project label edited to point outside allowed_projects is not
+	// followed
+	atlas.addProject("billing-id", "billing", "org")
+	atlas.putUser("billing-id", &mongodbatlas.DatabaseUser{Username: "victim", DatabaseName: "admin"})
+	atlas.putUser("project", &mongodbatlas.DatabaseUser{
+		Username:     "victim",
+		DatabaseName: "admin",
+		Labels:       append(managedUserLabels("app"), mongodbatlas.Label{Key: labelHomeProject, Value: "billing-id"}),
+	})
+	_, err = db.DeleteUser(context.Background(), dbplugin.DeleteUserRequest{Username: "victim"})
+	require.NoError(t, err)
+	require.NotNil(t, atlas.user("billing-id", "victim"))
+	require.Nil(t, atlas.user("project", "victim"))
+
+	// So are project labels of copies
+	atlas.putUser("project", &mongodbatlas.DatabaseUser{
+		Username:     "victim",
+		DatabaseName: "admin",
+		Labels:       append(managedUserLabels("app"), projectLabels([]projectStatement{{ProjectID: "billing-id"}})...),
+	})
+	_, err = db.DeleteUser(context.Background(), dbplugin.DeleteUserRequest{Username: "victim"})
+	require.NoError(t, err)
+	require.NotNil(t, atlas.user("billing-id", "victim"))
+	require.Nil(t, atlas.user("project", "victim"))
+}
+
+func TestResolveProject_RequiresAllowlist(t *testing.T) {
+	atlas := newFakeAtlas(t)
+	db := atlas.newTestDB(t, nil)
+
+	_, err := db.NewUser(context.Background(), dbplugin.NewUserRequest{
+		Statements: dbplugin.Statements{Commands: []string{`{"project_id": "other", "roles": [{"databaseName":"app","roleName":"read"}]}`}},
+		Password:   "password",
+	})
+	require.ErrorContains(t, err, "statements can only set project_id or project_name when allowed_projects is configured")
+
+	_, err = db.NewUser(context.Background(), dbplugin.NewUserRequest{
+		Statements: dbplugin.Statements{Commands: []string{testMultiProjectStatement}},
+		Password:   "password",
+	})
+	require.ErrorContains(t, err, "statements can only list projects when allowed_projects is configured")
+}
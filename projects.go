@@ -0,0 +1,173 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/atlas/mongodbatlas"
+)
+
+// labelProject is attached to the user in its own project once per
+// additional project the same user was created in, so that revocation and
+// renewal can find every copy.
+const labelProject = "vault-project"
+
+// projectStatement grants a user roles in an Atlas project other than the
+// configured one.
+type projectStatement struct {
+	ProjectID string               `json:"project_id"`
+	Roles     []mongodbatlas.Role  `json:"roles,omitempty"`
+	Scopes    []mongodbatlas.Scope `json:"scopes,omitempty"`
+}
+
+// checkProjects validates the additional projects of a creation statement.
+// Like a statement's own project_id, each one must be in allowed_projects.
+func (m *MongoDBAtlas) checkProjects(ctx context.Context, client *mongodbatlas.Client, projectID string, statement mongoDBAtlasStatement) error {
+	if len(statement.Projects) == 0 {
+		return nil
+	}
+	if statement.X509Type == x509TypeCustomer {
+		return errors.New("customer X.509 users can't be created in additional projects")
+	}
+	if len(m.AllowedProjects) == 0 {
+		return errors.New("statements can only list projects when allowed_projects is configured")
+	}
+
+	seen := map[string]struct{}{projectID: {}}
+	for _, project := range statement.Projects {
+		if project.ProjectID == "" {
+			return errors.New("project_id is required for each entry in projects")
+		}
+		if _, ok := seen[project.ProjectID]; ok {
+			return fmt.Errorf("project %q is listed more than once, or is the user's own project", project.ProjectID)
+		}
+		seen[project.ProjectID] = struct{}{}
+
+		if len(project.Roles) == 0 {
+			return fmt.Errorf("roles array is required for project %q", project.ProjectID)
+		}
+		if err := m.rolePolicy.check(project.Roles, project.Scopes); err != nil {
+			return fmt.Errorf("project %q: %w", project.ProjectID, err)
+		}
+	}
+
+	for _, project := range statement.Projects {
+		if _, err := m.allowedProject(ctx, client, project.ProjectID, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// projectLabels returns the labels recording the additional projects.
+func projectLabels(projects []projectStatement) []mongodbatlas.Label {
+	var labels []mongodbatlas.Label
+	for _, project := range projects {
+		labels = append(labels, mongodbatlas.Label{Key: labelProject, Value: project.ProjectID})
+	}
+	return labels
+}
+
+// userProjects returns the additional projects recorded on a user.
+func userProjects(user *mongodbatlas.DatabaseUser) []string {
+	var projects []string
+	for _, label := range user.Labels {
+		if label.Key == labelProject {
+			projects = append(projects, label.Value)
+		}
+	}
+	return projects
+}
+
+// additionalProjects returns the projects of a user's copies that the
+// connection may manage. Labels naming any other project are skipped.
+func (c *mongoDBAtlasConnectionProducer) additionalProjects(ctx context.Context, client *mongodbatlas.Client, user *mongodbatlas.DatabaseUser) ([]string, error) {
+	var projectIDs []string
+	for _, projectID := range userProjects(user) {
+		allowed, err := c.labelledProjectAllowed(ctx, client, projectID)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			c.logger.Warn("ignoring project label naming a project outside allowed_projects",
+				"username", user.Username, "project_id", projectID)
+			continue
+		}
+		projectIDs = append(projectIDs, projectID)
+	}
+	return projectIDs, nil
+}
+
+// createInProject creates a copy of the user in an additional project and
+// adds its access list entries there.
+func (m *MongoDBAtlas) createInProject(ctx context.Context, client *mongodbatlas.Client, homeProjectID string, user *mongodbatlas.DatabaseUser, project projectStatement, roleName string, accessList []string, expiration time.Time) error {
+	request := &mongodbatlas.DatabaseUser{
+		Username:     user.Username,
+		Password:     user.Password,
+		DatabaseName: user.DatabaseName,
+		X509Type:     user.X509Type,
+		Roles:        project.Roles,
+		Scopes:       project.Scopes,
+		Labels:       append(managedUserLabels(roleName), accessListLabels(accessList)...),
+	}
+	request.Labels = append(request.Labels, mongodbatlas.Label{Key: labelHomeProject, Value: homeProjectID})
+
+	_, _, err := client.DatabaseUsers.Create(ctx, project.ProjectID, request)
+	if err != nil {
+		return fmt.Errorf("error creating user in project %q: %w", project.ProjectID, err)
+	}
+
+	if err := m.addAccessList(ctx, client, project.ProjectID, accessList, expiration); err != nil {
+		return fmt.Errorf("project %q: %w", project.ProjectID, err)
+	}
+	return nil
+}
+
+// revokeInProjects revokes the copies of a user in its additional projects.
+// They are revoked before the user in its own project, which records
+// them, so a failed revocation can be retried.
+func (m *MongoDBAtlas) revokeInProjects(ctx context.Context, client *mongodbatlas.Client, user *mongodbatlas.DatabaseUser, statement mongoDBAtlasStatement) error {
+	projectIDs, err := m.additionalProjects(ctx, client, user)
+	if err != nil {
+		return err
+	}
+	for _, projectID := range projectIDs {
+		projectUser, _, err := client.DatabaseUsers.Get(ctx, statement.DatabaseName, projectID, user.Username)
+		if isAtlasNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("error reading user from project %q: %w", projectID, err)
+		}
+		if err := m.checkOwnership(projectUser, "delete", false); err != nil {
+			return fmt.Errorf("project %q: %w", projectID, err)
+		}
+
+		if statement.SoftRevoke {
+			err = m.softRevoke(ctx, client, projectID, projectUser, statement.RetainDays)
+			if err != nil {
+				return fmt.Errorf("project %q: %w", projectID, err)
+			}
+		} else {
+			_, err = client.DatabaseUsers.Delete(ctx, statement.DatabaseName, projectID, user.Username)
+			if err != nil && !isAtlasNotFound(err) {
+				return fmt.Errorf("error deleting user from project %q: %w", projectID, err)
+			}
+		}
+		m.recordUserDeleted(projectID)
+
+		err = m.releaseAccessList(ctx, client, projectID, user.Username, userAccessList(projectUser))
+		if err != nil {
+			return fmt.Errorf("project %q: %w", projectID, err)
+		}
+		if err := m.waitForDeployment(ctx, client, projectID, projectUser.Scopes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
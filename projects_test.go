@@ -0,0 +1,134 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	dbplugin "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/atlas/mongodbatlas"
+)
+
+const testMultiProjectStatement = `{
+	"roles": [{"databaseName":"app","roleName":"readWrite"}],
+	"access_list": ["198.51.100.7"],
+	"projects": [
+		{"project_id": "reporting", "roles": [{"databaseName":"reports","roleName":"read"}]},
+		{"project_id": "archive", "roles": [{"databaseName":"archive","roleName":"read"}]}
+	]
+}`
+
+func TestNewUser_MultipleProjects(t *testing.T) {
+	atlas := newFakeAtlas(t)
+	atlas.addProject("reporting", "reporting", "org")
+	atlas.addProject("archive", "archive", "org")
+	db := atlas.newTestDB(t, map[string]interface{}{
+		"username_template": "fixed-{{.RoleName}}",
+		"allowed_projects":  []string{"reporting", "archive"},
+	})
+
+	newUser := func() (dbplugin.NewUserResponse, error) {
+		return db.NewUser(context.Background(), dbplugin.NewUserRequest{
+			UsernameConfig: dbplugin.UsernameMetadata{DisplayName: "token", RoleName: "app"},
+			Statements:     dbplugin.Statements{Commands: []string{testMultiProjectStatement}},
+			Password:       "password",
+		})
+	}
+
+	resp, err := newUser()
+	require.NoError(t, err)
+
+	home := atlas.user("project", resp.Username)
+	require.ElementsMatch(t, []string{"reporting", "archive"}, userProjects(home))
+	reporting := atlas.user("reporting", resp.Username)
+	require.NotNil(t, reporting)
+	require.Equal(t, "password", reporting.Password)
+	require.Equal(t, []mongodbatlas.Role{{DatabaseName: "reports", RoleName: "read"}}, reporting.Roles)
+	require.NotNil(t, atlas.accessListEntry("archive", "198.51.100.7"))
+
+	_, err = db.UpdateUser(context.Background(), dbplugin.UpdateUserRequest{
+		Username: resp.Username,
+		Password: &dbplugin.ChangePassword{NewPassword: "rotated"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "rotated", atlas.user("archive", resp.Username).Password)
+
+	_, err = db.DeleteUser(context.Background(), dbplugin.DeleteUserRequest{Username: resp.Username})
+	require.NoError(t, err)
+	for _, project := range []string{"project", "reporting", "archive"} {
+		require.Nil(t, atlas.user(project, resp.Username), project)
+		require.Nil(t, atlas.accessListEntry(project, "198.51.100.7"), project)
+	}
+
+	// A failure in one project removes the user from the others, but never
+	// touches a user that already existed
+	atlas.putUser("archive", &mongodbatlas.DatabaseUser{Username: "fixed-app", DatabaseName: "admin", Password: "theirs"})
+	_, err = newUser()
+	require.ErrorContains(t, err, `error creating user in project "archive"`)
+	require.Nil(t, atlas.user("project", "fixed-app"))
+	require.Nil(t, atlas.user("reporting", "fixed-app"))
+	require.Equal(t, "theirs", atlas.user("archive", "fixed-app").Password)
+}
+
+func TestCheckProjects(t *testing.T) {
+	atlas := newFakeAtlas(t)
+	atlas.addProject("other", "other", "org")
+	atlas.addProject("billing", "billing", "org")
+	db := atlas.newTestDB(t, map[string]interface{}{"allowed_projects": "other"})
+	client, err := db.getConnection(context.Background(), "")
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		statement string
+		err       string
+	}{
+		"valid": {
+			statement: `{"projects": [{"project_id": "other", "roles": [{"roleName":"read","databaseName":"a"}]}]}`,
+		},
+		"not allowed": {
+			statement: `{"projects": [
+				{"project_id": "other", "roles": [{"roleName":"read","databaseName":"a"}]},
+				{"project_id": "billing", "roles": [{"roleName":"read","databaseName":"a"}]}
+			]}`,
+			err: `project "billing" (billing) is not in allowed_projects`,
+		},
+		"missing project": {
+			statement: `{"projects": [{"project_id": "missing", "roles": [{"roleName":"read","databaseName":"a"}]}]}`,
+			err:       `project "missing" does not exist`,
+		},
+		"missing project id": {
+			statement: `{"projects": [{"roles": [{"roleName":"read","databaseName":"a"}]}]}`,
+			err:       "project_id is required",
+		},
+		"configured project": {
+			statement: `{"projects": [{"project_id": "project", "roles": [{"roleName":"read","databaseName":"a"}]}]}`,
+			err:       `project "project" is listed more than once`,
+		},
+		"missing roles": {
+			statement: `{"projects": [{"project_id": "other"}]}`,
+			err:       `roles array is required for project "other"`,
+		},
+		"customer x509": {
+			statement: `{"x509Type": "CUSTOMER", "projects": [{"project_id": "other", "roles": [{"roleName":"read","databaseName":"a"}]}]}`,
+			err:       "customer X.509 users can't be created in additional projects",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var statement mongoDBAtlasStatement
+			require.NoError(t, json.Unmarshal([]byte(test.statement), &statement))
+
+			err := db.checkProjects(context.Background(), client, "project", statement)
+			if test.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, test.err)
+		})
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/atlas/mongodbatlas"
+)
+
+const defaultQuotaCacheTTL = 30 * time.Second
+
+// quotaConfig caps how many plugin managed users may exist at once.
+type quotaConfig struct {
+	MaxUsersPerProject int           `json:"max_users_per_project" structs:"max_users_per_project" mapstructure:"max_users_per_project"`
+	MaxUsersPerRole    int           `json:"max_users_per_role" structs:"max_users_per_role" mapstructure:"max_users_per_role"`
+	QuotaCacheTTL      time.Duration `json:"quota_cache_ttl" structs:"quota_cache_ttl" mapstructure:"quota_cache_ttl"`
+}
+
+func (q quotaConfig) validate() error {
+	if q.MaxUsersPerProject < 0 || q.MaxUsersPerRole < 0 {
+		return errors.New("max_users_per_project and max_users_per_role must not be negative")
+	}
+	if q.QuotaCacheTTL < 0 {
+		return errors.New("quota_cache_ttl must not be negative")
+	}
+	return nil
+}
+
+func (q quotaConfig) enabled() bool {
+	return q.MaxUsersPerProject > 0 || q.MaxUsersPerRole > 0
+}
+
+// managedUserCount is a cached count of the managed users in a project.
+type managedUserCount struct {
+	total   int
+	byRole  map[string]int
+	expires time.Time
+}
+
+// checkQuota returns an error if creating another user for the Vault role
+// would exceed the configured caps. Otherwise the user is counted against
+// the caps right away, so concurrent creations can't overshoot them, and the
+// returned func releases that reservation if the user is not created after
+// all. Counts are cached for quota_cache_ttl.
+func (c *mongoDBAtlasConnectionProducer) checkQuota(ctx context.Context, client *mongodbatlas.Client, projectID, roleName string) (func(), error) {
+	if !c.quotaConfig.enabled() {
+		return func() {}, nil
+	}
+
+	count, err := c.managedUserCount(ctx, client, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to count managed users for quota: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.MaxUsersPerProject > 0 && count.total >= c.MaxUsersPerProject {
+		return nil, fmt.Errorf("quota exceeded: project %q already has %d users managed by Vault, the maximum is %d",
+			projectID, count.total, c.MaxUsersPerProject)
+	}
+	if c.MaxUsersPerRole > 0 && roleName != "" && count.byRole[roleName] >= c.MaxUsersPerRole {
+		return nil, fmt.Errorf("quota exceeded: role %q already has %d users in project %q, the maximum is %d",
+			roleName, count.byRole[roleName], projectID, c.MaxUsersPerRole)
+	}
+
+	count.total++
+	if roleName != "" {
+		count.byRole[roleName]++
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+
+			count.total--
+			if roleName != "" {
+				count.byRole[roleName]--
+			}
+		})
+	}, nil
+}
+
+func (c *mongoDBAtlasConnectionProducer) managedUserCount(ctx context.Context, client *mongodbatlas.Client, projectID string) (*managedUserCount, error) {
+	c.mu.Lock()
+	count, ok := c.userCounts[projectID]
+	c.mu.Unlock()
+	if ok && time.Now().Before(count.expires) {
+		return count, nil
+	}
+
+	users, err := listUsers(ctx, client, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := c.QuotaCacheTTL
+	if ttl == 0 {
+		ttl = defaultQuotaCacheTTL
+	}
+	count = &managedUserCount{
+		byRole:  make(map[string]int),
+		expires: time.Now().Add(ttl),
+	}
+	for i := range users {
+		if !c.isManagedUser(&users[i]) || isRevokedUser(&users[i]) || isStaticRoleUser(&users[i]) {
+			continue
+		}
+		count.total++
+		if role, ok := labelValue(&users[i], labelVaultRole); ok {
+			count.byRole[role]++
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Keep a count refreshed concurrently, which may hold reservations
+	if current, ok := c.userCounts[projectID]; ok && time.Now().Before(current.expires) {
+		return current, nil
+	}
+	if c.userCounts == nil {
+		c.userCounts = make(map[string]*managedUserCount)
+	}
+	c.userCounts[projectID] = count
+	return count, nil
+}
+
+// recordUserDeleted drops the cached count since the deleted user's role is
+// not known.
+func (c *mongoDBAtlasConnectionProducer) recordUserDeleted(projectID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.userCounts, projectID)
+}
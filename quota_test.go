@@ -0,0 +1,77 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	dbplugin "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/atlas/mongodbatlas"
+)
+
+func TestNewUser_Quota(t *testing.T) {
+	atlas := newFakeAtlas(t)
+
+	// Users that aren't managed by the plugin don't count towards the caps
+	atlas.putUser("project", &mongodbatlas.DatabaseUser{Username: "human-dba"})
+	atlas.putUser("project", &mongodbatlas.DatabaseUser{
+		Username: "legacy-v-app",
+		Labels:   managedUserLabels("other"),
+	})
+
+	db := atlas.newTestDB(t, map[string]interface{}{
+		"max_users_per_project": 4,
+		"max_users_per_role":    "2",
+		"quota_cache_ttl":       "1m",
+	})
+
+	newUser := func(role string) error {
+		_, err := db.NewUser(context.Background(), dbplugin.NewUserRequest{
+			UsernameConfig: dbplugin.UsernameMetadata{DisplayName: "token", RoleName: role},
+			Statements:     dbplugin.Statements{Commands: []string{testMongoDBAtlasRole}},
+			Password:       "password",
+		})
+		return err
+	}
+
+	require.NoError(t, newUser("app"))
+	require.NoError(t, newUser("app"))
+	require.ErrorContains(t, newUser("app"), `quota exceeded: role "app" already has 2 users`)
+
+	require.NoError(t, newUser("batch"))
+	require.ErrorContains(t, newUser("batch"), `quota exceeded: project "project" already has 4 users`)
+
+	// Deleting a user frees up capacity
+	var appUser string
+	for _, user := range atlas.users["project"] {
+		if v, _ := labelValue(user, labelVaultRole); v == "app" {
+			appUser = user.Username
+		}
+	}
+	_, err := db.DeleteUser(context.Background(), dbplugin.DeleteUserRequest{Username: appUser})
+	require.NoError(t, err)
+	require.NoError(t, newUser("batch"))
+}
+
+func TestManagedUserCount_Pagination(t *testing.T) {
+	atlas := newFakeAtlas(t)
+	for i := 0; i < listPageSize+10; i++ {
+		atlas.putUser("project", &mongodbatlas.DatabaseUser{
+			Username: fmt.Sprintf("v-user-%04d", i),
+			Labels:   managedUserLabels("app"),
+		})
+	}
+
+	db := atlas.newTestDB(t, nil)
+	client, err := db.getConnection(context.Background(), "")
+	require.NoError(t, err)
+
+	count, err := db.managedUserCount(context.Background(), client, "project")
+	require.NoError(t, err)
+	require.Equal(t, listPageSize+10, count.total)
+	require.Equal(t, listPageSize+10, count.byRole["app"])
+}
@@ -0,0 +1,137 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"go.mongodb.org/atlas/mongodbatlas"
+)
+
+// reservedLabels are maintained by the plugin and can't be set in statements.
+var reservedLabels = map[string]struct{}{
+	labelManagedBy:  {},
+	labelVaultRole:  {},
+	labelAccessList: {},
+	labelRevokedAt:  {},
+	labelStaticRole: {},
+	labelProject:    {},
+
+	labelHomeProject: {},
+	labelCredential:  {},
+}
+
+// validateStatementLabels rejects labels that would overwrite the ones the
+// plugin relies on.
+func validateStatementLabels(labels []mongodbatlas.Label) error {
+	for _, label := range labels {
+		if label.Key == "" {
+			return errors.New("labels must have a key")
+		}
+		if _, ok := reservedLabels[label.Key]; ok {
+			return fmt.Errorf("label %q is reserved for use by the plugin", label.Key)
+		}
+	}
+	return nil
+}
+
+// parseRotationStatement parses static role rotation statements, which use
+// the same schema as creation statements. It returns nil when there are none.
+func (m *MongoDBAtlas) parseRotationStatement(commands []string) (*mongoDBAtlasStatement, error) {
+	if len(commands) == 0 {
+		return nil, nil
+	}
+	if len(commands) > 1 {
+		return nil, fmt.Errorf("only 1 rotation statement supported")
+	}
+
+	var statement mongoDBAtlasStatement
+	if err := json.Unmarshal([]byte(commands[0]), &statement); err != nil {
+		return nil, fmt.Errorf("error unmarshalling rotation statement: %w", err)
+	}
+	if len(statement.Roles) == 0 {
+		return nil, fmt.Errorf("roles array is required in rotation statement")
+	}
+	if err := m.rolePolicy.check(statement.Roles, statement.Scopes); err != nil {
+		return nil, err
+	}
+	if err := validateStatementLabels(statement.Labels); err != nil {
+		return nil, fmt.Errorf("invalid rotation statement: %w", err)
+	}
+	return &statement, nil
+}
+
+// reconcileUser sets the roles, scopes and labels of a rotation statement on
+// the update request and returns the fields in which the existing user had
+// drifted from them. Labels are only reconciled when the statement sets them,
+// and labels maintained by the plugin are always kept.
+func reconcileUser(request, existing *mongodbatlas.DatabaseUser, statement *mongoDBAtlasStatement) []string {
+	var drift []string
+
+	request.Roles = statement.Roles
+	if !sameRoles(existing.Roles, statement.Roles) {
+		drift = append(drift, "roles")
+	}
+
+	request.Scopes = statement.Scopes
+	if request.Scopes == nil {
+		request.Scopes = []mongodbatlas.Scope{}
+	}
+	if !sameScopes(existing.Scopes, statement.Scopes) {
+		drift = append(drift, "scopes")
+	}
+
+	if statement.Labels != nil {
+		var labels []mongodbatlas.Label
+		for _, label := range existing.Labels {
+			if _, ok := reservedLabels[label.Key]; ok {
+				labels = append(labels, label)
+			}
+		}
+		request.Labels = append(labels, statement.Labels...)
+		if !sameLabels(existing.Labels, request.Labels) {
+			drift = append(drift, "labels")
+		}
+	}
+
+	return drift
+}
+
+// sameScopes compares two scope lists, ignoring order.
+func sameScopes(a, b []mongodbatlas.Scope) bool {
+	return sameKeys(a, b, func(s mongodbatlas.Scope) string {
+		return s.Type + "\x00" + s.Name
+	})
+}
+
+// sameLabels compares two label lists, ignoring order.
+func sameLabels(a, b []mongodbatlas.Label) bool {
+	return sameKeys(a, b, func(l mongodbatlas.Label) string {
+		return l.Key + "\x00" + l.Value
+	})
+}
+
+func sameKeys[T any](a, b []T, key func(T) string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	keys := func(items []T) []string {
+		out := make([]string, 0, len(items))
+		for _, item := range items {
+			out = append(out, key(item))
+		}
+		sort.Strings(out)
+		return out
+	}
+	ka, kb := keys(a), keys(b)
+	for i := range ka {
+		if ka[i] != kb[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,92 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"context"
+	"testing"
+
+	dbplugin "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/atlas/mongodbatlas"
+)
+
+func TestUpdateUser_ReconcileStaticRole(t *testing.T) {
+	atlas := newFakeAtlas(t)
+	db := atlas.newTestDB(t, nil)
+	atlas.putUser("project", &mongodbatlas.DatabaseUser{
+		Username:     "static",
+		DatabaseName: "admin",
+		Password:     "old-password",
+		Roles:        []mongodbatlas.Role{{DatabaseName: "admin", RoleName: "atlasAdmin"}},
+		Scopes:       []mongodbatlas.Scope{{Name: "old", Type: "CLUSTER"}},
+		Labels: []mongodbatlas.Label{
+			{Key: labelManagedBy, Value: labelManagedByValue},
+			{Key: "team", Value: "old"},
+		},
+	})
+
+	statement := `{
+		"roles": [{"databaseName":"app","roleName":"readWrite"}],
+		"labels": [{"key":"team","value":"payments"}]
+	}`
+	_, err := db.UpdateUser(context.Background(), dbplugin.UpdateUserRequest{
+		Username: "static",
+		Password: &dbplugin.ChangePassword{
+			NewPassword: "new-password",
+			Statements:  dbplugin.Statements{Commands: []string{statement}},
+		},
+	})
+	require.NoError(t, err)
+
+	user := atlas.user("project", "static")
+	require.Equal(t, "new-password", user.Password)
+	require.Equal(t, []mongodbatlas.Role{{DatabaseName: "app", RoleName: "readWrite"}}, user.Roles)
+	require.Empty(t, user.Scopes)
+	require.ElementsMatch(t, []mongodbatlas.Label{
+		{Key: labelManagedBy, Value: labelManagedByValue},
+		{Key: labelStaticRole, Value: "true"},
+		{Key: "team", Value: "payments"},
+	}, user.Labels)
+
+	_, err = db.UpdateUser(context.Background(), dbplugin.UpdateUserRequest{
+		Username: "static",
+		Password: &dbplugin.ChangePassword{
+			NewPassword: "new-password",
+			Statements:  dbplugin.Statements{Commands: []string{`{"roles": [], "labels": [{"key":"managed-by","value":"me"}]}`}},
+		},
+	})
+	require.ErrorContains(t, err, "roles array is required in rotation statement")
+}
+
+func TestReconcileUser(t *testing.T) {
+	existing := &mongodbatlas.DatabaseUser{
+		Roles:  []mongodbatlas.Role{{DatabaseName: "a", RoleName: "read"}, {DatabaseName: "b", RoleName: "read"}},
+		Scopes: []mongodbatlas.Scope{{Name: "c1", Type: "CLUSTER"}},
+		Labels: []mongodbatlas.Label{{Key: labelVaultRole, Value: "app"}, {Key: "team", Value: "x"}},
+	}
+
+	request := &mongodbatlas.DatabaseUser{}
+	drift := reconcileUser(request, existing, &mongoDBAtlasStatement{
+		Roles:  []mongodbatlas.Role{{DatabaseName: "b", RoleName: "read"}, {DatabaseName: "a", RoleName: "read"}},
+		Scopes: []mongodbatlas.Scope{{Name: "c1", Type: "CLUSTER"}},
+	})
+	require.Empty(t, drift)
+	require.Nil(t, request.Labels)
+
+	request = &mongodbatlas.DatabaseUser{}
+	drift = reconcileUser(request, existing, &mongoDBAtlasStatement{
+		Roles:  []mongodbatlas.Role{{DatabaseName: "a", RoleName: "read"}},
+		Labels: []mongodbatlas.Label{{Key: "team", Value: "y"}},
+	})
+	require.Equal(t, []string{"roles", "scopes", "labels"}, drift)
+	require.Equal(t, []mongodbatlas.Scope{}, request.Scopes)
+	require.Equal(t, []mongodbatlas.Label{{Key: labelVaultRole, Value: "app"}, {Key: "team", Value: "y"}}, request.Labels)
+}
+
+func TestValidateStatementLabels(t *testing.T) {
+	require.NoError(t, validateStatementLabels([]mongodbatlas.Label{{Key: "team", Value: "x"}}))
+	require.ErrorContains(t, validateStatementLabels([]mongodbatlas.Label{{Key: labelRevokedAt}}), "reserved")
+	require.ErrorContains(t, validateStatementLabels([]mongodbatlas.Label{{Value: "x"}}), "must have a key")
+}
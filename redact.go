@@ -0,0 +1,117 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// redactor maps secrets to the placeholders that replace them in errors.
+type redactor map[string]string
+
+// add maps a secret to the placeholder, along with the forms it takes once
+// escaped in a URL.
+func (r redactor) add(secret, placeholder string) {
+	if secret == "" {
+		return
+	}
+	r[secret] = placeholder
+	r[url.QueryEscape(secret)] = placeholder
+	r[url.PathEscape(secret)] = placeholder
+	r[strings.TrimPrefix(url.UserPassword("", secret).String(), ":")] = placeholder
+}
+
+// addURL adds the password in the userinfo of a connection URL. The URL may
+// contain {{username}} and {{password}} placeholders, which url.Parse
+// rejects, so the userinfo is split off by hand.
+func (r redactor) addURL(connectionURL, placeholder string) {
+	_, rest, ok := strings.Cut(connectionURL, "://")
+	if !ok {
+		return
+	}
+	if end := strings.IndexAny(rest, "/?"); end >= 0 {
+		rest = rest[:end]
+	}
+	at := strings.LastIndex(rest, "@")
+	if at < 0 {
+		return
+	}
+	_, password, ok := strings.Cut(rest[:at], ":")
+	if !ok || password == "" || password == "{{password}}" {
+		return
+	}
+	r.add(password, placeholder)
+	if unescaped, err := url.PathUnescape(password); err == nil {
+		r.add(unescaped, placeholder)
+	}
+}
+
+// redact replaces every secret in the error's message. The error is returned
+// unchanged, with its chain intact, if it contains none.
+func (r redactor) redact(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	// Longer secrets go first so none is left partially visible by a
+	// shorter one it contains
+	secrets := make([]string, 0, len(r))
+	for secret := range r {
+		secrets = append(secrets, secret)
+	}
+	sort.Slice(secrets, func(i, j int) bool {
+		return len(secrets[i]) > len(secrets[j])
+	})
+
+	message := err.Error()
+	redacted := message
+	for _, secret := range secrets {
+		redacted = strings.ReplaceAll(redacted, secret, r[secret])
+	}
+	if redacted == message {
+		return err
+	}
+	return errors.New(redacted)
+}
+
+// secrets returns every secret of the connection: the API keys, passwords in
+// connection URLs, and passwords the plugin set and remembers.
+func (c *connectionState) secrets() redactor {
+	r := redactor{}
+
+	c.mu.Lock()
+	r.add(c.PrivateKey, "[private_key]")
+	r.add(c.SecondaryPrivateKey, "[secondary_private_key]")
+	for _, password := range c.rotatedPasswords {
+		r.add(password, "[password]")
+	}
+	for name, set := range c.Credentials {
+		r.add(set.PrivateKey, fmt.Sprintf("[credentials.%s.private_key]", name))
+	}
+	r.add(c.SessionAdminPassword, "[session_admin_password]")
+	c.mu.Unlock()
+
+	r.addURL(c.VerifyConnectionURL, "[verify_connection_url_password]")
+	for _, clusterURL := range c.SessionClusterURLs {
+		r.addURL(clusterURL, "[session_cluster_url_password]")
+	}
+	return r
+}
+
+// redact removes the connection's secrets, and the passwords of the current
+// request, from an error.
+func (c *connectionState) redact(err error, passwords ...string) error {
+	if err == nil {
+		return nil
+	}
+	r := c.secrets()
+	for _, password := range passwords {
+		r.add(password, "[password]")
+	}
+	return r.redact(err)
+}
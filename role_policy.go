@@ -0,0 +1,129 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/atlas/mongodbatlas"
+)
+
+// readOnlyAdminRoles are the built-in roles that can be granted on the admin
+// database without allowing writes. Any other role on admin, including custom
+// roles, is treated as a write role.
+var readOnlyAdminRoles = map[string]struct{}{
+	"read":            {},
+	"readAnyDatabase": {},
+	"clusterMonitor":  {},
+}
+
+// rolePolicy guards which roles and scopes creation statements may grant.
+// Names are matched as globs, or as regular expressions when prefixed with
+// "regex:".
+type rolePolicy struct {
+	AllowedDatabaseRoles []string `json:"allowed_database_roles" structs:"allowed_database_roles" mapstructure:"allowed_database_roles"`
+	DeniedDatabaseRoles  []string `json:"denied_database_roles" structs:"denied_database_roles" mapstructure:"denied_database_roles"`
+	AllowedDatabases     []string `json:"allowed_databases" structs:"allowed_databases" mapstructure:"allowed_databases"`
+	AllowedCollections   []string `json:"allowed_collections" structs:"allowed_collections" mapstructure:"allowed_collections"`
+	RequireScopes        bool     `json:"require_scopes" structs:"require_scopes" mapstructure:"require_scopes"`
+	DenyAdminWriteRoles  bool     `json:"deny_admin_write_roles" structs:"deny_admin_write_roles" mapstructure:"deny_admin_write_roles"`
+}
+
+// validate checks that every pattern in the policy can be compiled.
+func (p rolePolicy) validate() error {
+	for _, patterns := range [][]string{p.AllowedDatabaseRoles, p.DeniedDatabaseRoles, p.AllowedDatabases, p.AllowedCollections} {
+		for _, pattern := range patterns {
+			if _, err := matchPattern(pattern, ""); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// check returns an error if the roles or scopes violate the policy.
+func (p rolePolicy) check(roles []mongodbatlas.Role, scopes []mongodbatlas.Scope) error {
+	if p.RequireScopes && len(scopes) == 0 {
+		return errors.New("role policy violation: scopes must be set to limit the user to specific clusters or data lakes")
+	}
+
+	for _, role := range roles {
+		if err := p.checkRole(role); err != nil {
+			return fmt.Errorf("role policy violation: %w", err)
+		}
+	}
+	return nil
+}
+
+func (p rolePolicy) checkRole(role mongodbatlas.Role) error {
+	database := role.DatabaseName
+	if database == "" {
+		database = "admin"
+	}
+
+	denied, err := matchAny(p.DeniedDatabaseRoles, role.RoleName)
+	if err != nil {
+		return err
+	}
+	if denied {
+		return fmt.Errorf("role %q is denied", role.RoleName)
+	}
+
+	if len(p.AllowedDatabaseRoles) > 0 {
+		allowed, err := matchAny(p.AllowedDatabaseRoles, role.RoleName)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return fmt.Errorf("role %q is not in the allowed roles", role.RoleName)
+		}
+	}
+
+	if len(p.AllowedDatabases) > 0 {
+		allowed, err := matchAny(p.AllowedDatabases, database)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return fmt.Errorf("role %q on database %q is not in the allowed databases", role.RoleName, database)
+		}
+	}
+
+	if len(p.AllowedCollections) > 0 {
+		// A role without a collection applies to every collection
+		if role.CollectionName == "" {
+			return fmt.Errorf("role %q must name a collection when allowed_collections is set", role.RoleName)
+		}
+		allowed, err := matchAny(p.AllowedCollections, role.CollectionName)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return fmt.Errorf("role %q on collection %q is not in the allowed collections", role.RoleName, role.CollectionName)
+		}
+	}
+
+	if p.DenyAdminWriteRoles && database == "admin" {
+		if _, ok := readOnlyAdminRoles[role.RoleName]; !ok {
+			return fmt.Errorf("role %q grants write access on the admin database", role.RoleName)
+		}
+	}
+
+	return nil
+}
+
+// matchAny reports whether value matches at least one of the patterns.
+func matchAny(patterns []string, value string) (bool, error) {
+	for _, pattern := range patterns {
+		matched, err := matchPattern(pattern, value)
+		if err != nil {
+			return false, err
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,116 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"context"
+	"testing"
+
+	dbplugin "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/atlas/mongodbatlas"
+)
+
+func TestRolePolicy_Check(t *testing.T) {
+	clusterScope := []mongodbatlas.Scope{{Name: "cluster0", Type: "CLUSTER"}}
+
+	tests := map[string]struct {
+		policy  rolePolicy
+		roles   []mongodbatlas.Role
+		scopes  []mongodbatlas.Scope
+		wantErr string
+	}{
+		"empty policy allows anything": {
+			roles: []mongodbatlas.Role{{RoleName: "atlasAdmin", DatabaseName: "admin"}},
+		},
+		"denied role": {
+			policy:  rolePolicy{DeniedDatabaseRoles: []string{"atlasAdmin", "*AnyDatabase"}},
+			roles:   []mongodbatlas.Role{{RoleName: "readWriteAnyDatabase", DatabaseName: "admin"}},
+			wantErr: `role "readWriteAnyDatabase" is denied`,
+		},
+		"role not allowed": {
+			policy:  rolePolicy{AllowedDatabaseRoles: []string{"read"}},
+			roles:   []mongodbatlas.Role{{RoleName: "readWrite", DatabaseName: "app"}},
+			wantErr: "not in the allowed roles",
+		},
+		"database allowed": {
+			policy: rolePolicy{AllowedDatabases: []string{"app-*"}},
+			roles:  []mongodbatlas.Role{{RoleName: "readWrite", DatabaseName: "app-billing"}},
+		},
+		"database not allowed": {
+			policy:  rolePolicy{AllowedDatabases: []string{"app-*"}},
+			roles:   []mongodbatlas.Role{{RoleName: "readWrite", DatabaseName: "payroll"}},
+			wantErr: "not in the allowed databases",
+		},
+		"missing database counts as admin": {
+			policy:  rolePolicy{AllowedDatabases: []string{"app"}},
+			roles:   []mongodbatlas.Role{{RoleName: "read"}},
+			wantErr: `on database "admin"`,
+		},
+		"collection not allowed": {
+			policy:  rolePolicy{AllowedCollections: []string{"regex:orders|invoices"}},
+			roles:   []mongodbatlas.Role{{RoleName: "read", DatabaseName: "app", CollectionName: "users"}},
+			wantErr: "not in the allowed collections",
+		},
+		"collection allowed": {
+			policy: rolePolicy{AllowedCollections: []string{"regex:orders|invoices"}},
+			roles:  []mongodbatlas.Role{{RoleName: "read", DatabaseName: "app", CollectionName: "orders"}},
+		},
+		"missing collection": {
+			policy:  rolePolicy{AllowedCollections: []string{"orders"}},
+			roles:   []mongodbatlas.Role{{RoleName: "readWrite", DatabaseName: "app"}},
+			wantErr: `role "readWrite" must name a collection when allowed_collections is set`,
+		},
+		"scopes required": {
+			policy:  rolePolicy{RequireScopes: true},
+			roles:   []mongodbatlas.Role{{RoleName: "read", DatabaseName: "app"}},
+			wantErr: "scopes must be set",
+		},
+		"scopes present": {
+			policy: rolePolicy{RequireScopes: true},
+			roles:  []mongodbatlas.Role{{RoleName: "read", DatabaseName: "app"}},
+			scopes: clusterScope,
+		},
+		"admin read allowed": {
+			policy: rolePolicy{DenyAdminWriteRoles: true},
+			roles:  []mongodbatlas.Role{{RoleName: "readAnyDatabase", DatabaseName: "admin"}},
+		},
+		"admin write denied": {
+			policy:  rolePolicy{DenyAdminWriteRoles: true},
+			roles:   []mongodbatlas.Role{{RoleName: "readWriteAnyDatabase", DatabaseName: "admin"}},
+			wantErr: "grants write access on the admin database",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, tt.policy.validate())
+
+			err := tt.policy.check(tt.roles, tt.scopes)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, "role policy violation")
+				require.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewUser_RolePolicy(t *testing.T) {
+	atlas := newFakeAtlas(t)
+	db := atlas.newTestDB(t, map[string]interface{}{
+		"denied_database_roles":  []interface{}{"atlasAdmin"},
+		"deny_admin_write_roles": true,
+	})
+
+	_, err := db.NewUser(context.Background(), dbplugin.NewUserRequest{
+		Statements: dbplugin.Statements{
+			Commands: []string{testMongoDBAtlasRole},
+		},
+		Password: "password",
+	})
+	require.ErrorContains(t, err, "role policy violation")
+	require.Empty(t, atlas.requests)
+}
@@ -0,0 +1,91 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/atlas/mongodbatlas"
+)
+
+// rotationConfig enables two-phase password rotation: the new password is
+// verified against the cluster and the previous one restored if it fails.
+type rotationConfig struct {
+	TwoPhaseRotation bool `json:"two_phase_rotation" structs:"two_phase_rotation" mapstructure:"two_phase_rotation"`
+}
+
+func (r rotationConfig) validate(v verifyConfig) error {
+	if r.TwoPhaseRotation && v.VerifyConnectionURL == "" {
+		return errors.New("verify_connection_url must be set when two_phase_rotation is enabled")
+	}
+	return nil
+}
+
+// verifyRotation verifies a rotated password. Atlas already has the new
+// password by then, so a failed verification doesn't fail the rotation:
+// Vault must store the new password to stay in sync, and is only warned.
+// With two-phase rotation a failed verification instead restores the
+// previous password, so the account keeps working with the credentials
+// Vault still holds in every project. Atlas never returns passwords and
+// Vault doesn't pass the current one, so only passwords the plugin itself
+// set and verified since it started can be restored. The first of
+// projectIDs is the user's own project; users outside the configured
+// project can't be verified.
+func (c *mongoDBAtlasConnectionProducer) verifyRotation(ctx context.Context, client *mongodbatlas.Client, projectIDs []string, username, password string) error {
+	if !c.verifies(projectIDs[0]) {
+		return nil
+	}
+	err := c.verifyCredentials(ctx, projectIDs[0], username, password)
+	if !c.TwoPhaseRotation {
+		if err != nil {
+			c.logger.Warn("rotated password failed verification, keeping the new password",
+				"username", username, "error", c.redact(err, password))
+		}
+		return nil
+	}
+
+	if err == nil {
+		c.logger.Info("verified rotated password", "username", username)
+		c.mu.Lock()
+		if c.rotatedPasswords == nil {
+			c.rotatedPasswords = make(map[string]string)
+		}
+		c.rotatedPasswords[username] = password
+		c.mu.Unlock()
+		return nil
+	}
+
+	c.mu.Lock()
+	previous, ok := c.rotatedPasswords[username]
+	c.mu.Unlock()
+	if !ok {
+		c.logger.Warn("rotated password failed verification and the previous password is not known, keeping the new password",
+			"username", username, "error", c.redact(err, password))
+		return nil
+	}
+
+	c.logger.Warn("rotated password failed verification, rolling back to the previous password", "username", username)
+	ctx, cancel := cleanupContext(ctx)
+	defer cancel()
+
+	var rollbackErr error
+	for _, projectID := range projectIDs {
+		_, _, rollbackErr = client.DatabaseUsers.Update(ctx, projectID, username, &mongodbatlas.DatabaseUser{
+			Password: previous,
+		})
+		if rollbackErr != nil {
+			break
+		}
+	}
+	if rollbackErr != nil {
+		c.logger.Error("failed to roll back to the previous password", "username", username,
+			"error", c.redact(rollbackErr, password, previous))
+		return fmt.Errorf("%w; rolling back to the previous password also failed: %v", err, rollbackErr)
+	}
+
+	c.logger.Info("rolled back to the previous password", "username", username)
+	return fmt.Errorf("%w; the previous password was restored", err)
+}
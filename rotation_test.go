@@ -0,0 +1,74 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	dbplugin "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/atlas/mongodbatlas"
+)
+
+func TestUpdateUser_TwoPhaseRotation(t *testing.T) {
+	atlas := newFakeAtlas(t)
+	db := atlas.newTestDB(t, map[string]interface{}{
+		"verify_connection_url": "mongodb+srv://cluster0.example.mongodb.net",
+		"verify_timeout":        "20ms",
+		"two_phase_rotation":    true,
+	})
+	db.verifyInterval = time.Millisecond
+	atlas.putUser("project", &mongodbatlas.DatabaseUser{Username: "static", DatabaseName: "admin", Password: "original"})
+
+	broken := ""
+	db.pingCluster = func(_ context.Context, _, _, password string) error {
+		if password == broken {
+			return errors.New("authentication failed")
+		}
+		return nil
+	}
+	rotate := func(password string) error {
+		_, err := db.UpdateUser(context.Background(), dbplugin.UpdateUserRequest{
+			Username: "static",
+			Password: &dbplugin.ChangePassword{NewPassword: password},
+		})
+		return err
+	}
+
+	// The previous password is unknown until the plugin has set one, so the
+	// new password is kept and Vault stores it
+	broken = "first"
+	require.NoError(t, rotate("first"))
+	require.Equal(t, "first", atlas.user("project", "static").Password)
+
+	require.NoError(t, rotate("second"))
+
+	broken = "third"
+	err := rotate("third")
+	require.ErrorContains(t, err, "the previous password was restored")
+	require.Equal(t, "second", atlas.user("project", "static").Password)
+
+	// The verified password survives reinitializing the connection
+	_, err = db.Initialize(context.Background(), dbplugin.InitializeRequest{Config: db.RawConfig})
+	require.NoError(t, err)
+	broken = "fourth"
+	err = rotate("fourth")
+	require.ErrorContains(t, err, "the previous password was restored")
+	require.Equal(t, "second", atlas.user("project", "static").Password)
+}
+
+func TestInitialize_TwoPhaseRotationRequiresVerification(t *testing.T) {
+	db := new()
+	_, err := db.Initialize(context.Background(), dbplugin.InitializeRequest{
+		Config: map[string]interface{}{
+			"public_key":         "public",
+			"private_key":        "private",
+			"two_phase_rotation": true,
+		},
+	})
+	require.ErrorContains(t, err, "verify_connection_url must be set when two_phase_rotation is enabled")
+}
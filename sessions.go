@@ -0,0 +1,170 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const sessionTerminationTimeout = 30 * time.Second
+
+// sessionConfig enables killing the sessions and operations of revoked users
+// so that existing connections can't outlive the credentials. The admin user
+// needs the inprog and killop privileges on every cluster, e.g. atlasAdmin.
+type sessionConfig struct {
+	TerminateSessions    bool     `json:"terminate_sessions" structs:"terminate_sessions" mapstructure:"terminate_sessions"`
+	SessionClusterURLs   []string `json:"session_cluster_urls" structs:"session_cluster_urls" mapstructure:"session_cluster_urls"`
+	SessionAdminUsername string   `json:"session_admin_username" structs:"session_admin_username" mapstructure:"session_admin_username"`
+	SessionAdminPassword string   `json:"session_admin_password" structs:"session_admin_password" mapstructure:"session_admin_password"`
+
+	// SessionAdminPasswordFile is read like private_key_file, which keeps the
+	// password out of the config Vault returns on reads.
+	SessionAdminPasswordFile string `json:"session_admin_password_file" structs:"session_admin_password_file" mapstructure:"session_admin_password_file"`
+}
+
+func (s sessionConfig) validate() error {
+	if s.SessionAdminPassword != "" && s.SessionAdminPasswordFile != "" {
+		return errors.New("only one of session_admin_password and session_admin_password_file can be configured")
+	}
+	if !s.TerminateSessions {
+		return nil
+	}
+	if len(s.SessionClusterURLs) == 0 {
+		return errors.New("session_cluster_urls must be set when terminate_sessions is enabled")
+	}
+	if s.SessionAdminUsername == "" || (s.SessionAdminPassword == "" && s.SessionAdminPasswordFile == "") {
+		return errors.New("session_admin_username and session_admin_password or session_admin_password_file " +
+			"must be set when terminate_sessions is enabled")
+	}
+	for _, u := range s.SessionClusterURLs {
+		if !strings.HasPrefix(u, "mongodb://") && !strings.HasPrefix(u, "mongodb+srv://") {
+			return errors.New("session_cluster_urls must start with mongodb:// or mongodb+srv://")
+		}
+	}
+	return nil
+}
+
+// terminateSessions kills the sessions and operations of a revoked user on
+// every configured cluster. The user is already gone by the time this runs,
+// so failures are logged rather than returned.
+func (c *mongoDBAtlasConnectionProducer) terminateSessions(ctx context.Context, username, authDB string) {
+	if !c.TerminateSessions {
+		return
+	}
+
+	for i, clusterURL := range c.SessionClusterURLs {
+		sessions, ops, err := c.terminateClusterSessions(ctx, clusterURL, username, authDB)
+		if err != nil {
+			c.logger.Error("failed to terminate sessions of revoked user",
+				"username", username, "cluster", i, "error", c.redact(err))
+			continue
+		}
+		c.logger.Info("terminated sessions of revoked user",
+			"username", username, "cluster", i, "sessions", sessions, "operations", ops)
+	}
+}
+
+func (c *mongoDBAtlasConnectionProducer) terminateClusterSessions(ctx context.Context, clusterURL, username, authDB string) (int, int, error) {
+	ctx, cancel := context.WithTimeout(ctx, sessionTerminationTimeout)
+	defer cancel()
+
+	c.mu.Lock()
+	password := c.SessionAdminPassword
+	c.mu.Unlock()
+
+	opts := options.Client().ApplyURI(clusterURL).SetAuth(options.Credential{
+		AuthSource: "admin",
+		Username:   c.SessionAdminUsername,
+		Password:   password,
+	})
+	client, err := mongo.Connect(ctx, opts)
+	if err != nil {
+		return 0, 0, errors.New("unable to connect to cluster")
+	}
+	defer client.Disconnect(context.Background())
+
+	admin := client.Database("admin")
+	cursor, err := admin.Aggregate(ctx, currentOpPipeline(username, authDB))
+	if err != nil {
+		return 0, 0, err
+	}
+	var ops []bson.M
+	if err := cursor.All(ctx, &ops); err != nil {
+		return 0, 0, err
+	}
+
+	sessions, opIDs := sessionKillPlan(ops)
+	if len(sessions) > 0 {
+		err := admin.RunCommand(ctx, bson.D{{Key: "killSessions", Value: sessions}}).Err()
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	for _, opID := range opIDs {
+		err := admin.RunCommand(ctx, bson.D{{Key: "killOp", Value: 1}, {Key: "op", Value: opID}}).Err()
+		if err != nil {
+			return len(sessions), 0, err
+		}
+	}
+	return len(sessions), len(opIDs), nil
+}
+
+// currentOpPipeline finds every operation, idle session and idle cursor
+// belonging to the user. Idle sessions don't list their users, so they are
+// matched on the session's uid, the SHA-256 hash of "user@db".
+func currentOpPipeline(username, authDB string) mongo.Pipeline {
+	uid := sha256.Sum256([]byte(username + "@" + authDB))
+
+	return mongo.Pipeline{
+		{{Key: "$currentOp", Value: bson.D{
+			{Key: "allUsers", Value: true},
+			{Key: "idleSessions", Value: true},
+			{Key: "idleCursors", Value: true},
+		}}},
+		{{Key: "$match", Value: bson.D{
+			{Key: "$or", Value: bson.A{
+				bson.D{{Key: "effectiveUsers.user", Value: username}},
+				bson.D{{Key: "lsid.uid", Value: primitive.Binary{Subtype: bson.TypeBinaryGeneric, Data: uid[:]}}},
+			}},
+		}}},
+	}
+}
+
+// sessionKillPlan splits $currentOp results into the logical sessions to pass
+// to killSessions and the operations without a session to pass to killOp.
+func sessionKillPlan(ops []bson.M) (bson.A, bson.A) {
+	var sessions, opIDs bson.A
+	seen := make(map[string]struct{})
+	for _, op := range ops {
+		if lsid, ok := op["lsid"].(bson.M); ok && lsid["id"] != nil {
+			key := bsonKey(lsid["id"])
+			if _, ok := seen[key]; !ok {
+				seen[key] = struct{}{}
+				sessions = append(sessions, bson.M{"id": lsid["id"]})
+			}
+			continue
+		}
+		if opID, ok := op["opid"]; ok {
+			opIDs = append(opIDs, opID)
+		}
+	}
+	return sessions, opIDs
+}
+
+func bsonKey(v interface{}) string {
+	raw, err := bson.Marshal(bson.M{"v": v})
+	if err != nil {
+		return ""
+	}
+	return string(raw)
+}
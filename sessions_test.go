@@ -0,0 +1,76 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	dbplugin "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSessionKillPlan(t *testing.T) {
+	session := primitive.Binary{Subtype: bson.TypeBinaryUUID, Data: []byte("0123456789abcdef")}
+	other := primitive.Binary{Subtype: bson.TypeBinaryUUID, Data: []byte("fedcba9876543210")}
+
+	sessions, opIDs := sessionKillPlan([]bson.M{
+		{"type": "op", "opid": int32(1), "lsid": bson.M{"id": session}},
+		{"type": "idleCursor", "lsid": bson.M{"id": session}},
+		{"type": "idleSession", "lsid": bson.M{"id": other}},
+		{"type": "op", "opid": "shard0:42"},
+	})
+
+	require.Equal(t, bson.A{bson.M{"id": session}, bson.M{"id": other}}, sessions)
+	require.Equal(t, bson.A{"shard0:42"}, opIDs)
+}
+
+func TestSessionConfig_Validate(t *testing.T) {
+	db := new()
+	_, err := db.Initialize(context.Background(), dbplugin.InitializeRequest{
+		Config: map[string]interface{}{
+			"public_key":           "public",
+			"private_key":          "private",
+			"terminate_sessions":   true,
+			"session_cluster_urls": "mongodb+srv://cluster0.example.mongodb.net",
+		},
+	})
+	require.ErrorContains(t, err, "session_admin_username and session_admin_password or session_admin_password_file must be set")
+
+	_, err = db.Initialize(context.Background(), dbplugin.InitializeRequest{
+		Config: map[string]interface{}{
+			"public_key":                  "public",
+			"private_key":                 "private",
+			"session_admin_password":      "s3ssion-admin",
+			"session_admin_password_file": "/password",
+		},
+	})
+	require.ErrorContains(t, err, "only one of session_admin_password and session_admin_password_file can be configured")
+}
+
+func TestSessionConfig_PasswordFile(t *testing.T) {
+	passwordFile := filepath.Join(t.TempDir(), "session_admin_password")
+	require.NoError(t, os.WriteFile(passwordFile, []byte("first-password\n"), 0o600))
+
+	atlas := newFakeAtlas(t)
+	db := atlas.newTestDB(t, map[string]interface{}{
+		"terminate_sessions":          true,
+		"session_cluster_urls":        "mongodb+srv://cluster0.example.mongodb.net",
+		"session_admin_username":      "admin",
+		"session_admin_password_file": passwordFile,
+	})
+	db.credentialCheckInterval = time.Nanosecond
+	require.Equal(t, "first-password", db.SessionAdminPassword)
+
+	require.NoError(t, os.WriteFile(passwordFile, []byte("second-password"), 0o600))
+	_, err := db.getConnection(context.Background(), "")
+	require.NoError(t, err)
+	require.Equal(t, "second-password", db.SessionAdminPassword)
+	require.Equal(t, "[session_admin_password]", db.secretValues()["second-password"])
+}
@@ -0,0 +1,91 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-secure-stdlib/base62"
+	"go.mongodb.org/atlas/mongodbatlas"
+)
+
+const (
+	// labelRevokedAt marks users that were soft revoked and are only retained
+	// so their audit log identity can still be correlated.
+	labelRevokedAt = "revoked-at"
+
+	defaultRetainDays = 7
+
+	// Atlas refuses to schedule the deletion of a database user more than a
+	// week in the future.
+	maxRetainDays = 7
+
+	scrambledPasswordLength = 40
+)
+
+// revokedRole is the placeholder role left on soft revoked users, since
+// Atlas requires every user to have at least one role. It grants read access
+// to a database that holds no data.
+var revokedRole = mongodbatlas.Role{
+	RoleName:     "read",
+	DatabaseName: "vault_revoked",
+}
+
+// softRevoke strips a user of its access instead of deleting it. Its roles are
+// replaced with a no-access placeholder, its password is scrambled and it is
+// labelled with the revocation time. Atlas deletes it after retainDays.
+func (c *mongoDBAtlasConnectionProducer) softRevoke(ctx context.Context, client *mongodbatlas.Client, projectID string, user *mongodbatlas.DatabaseUser, retainDays int) error {
+	if retainDays == 0 {
+		retainDays = defaultRetainDays
+	}
+	if retainDays < 0 || retainDays > maxRetainDays {
+		return fmt.Errorf("retain_days must be between 1 and %d", maxRetainDays)
+	}
+
+	now := time.Now().UTC()
+
+	// Access list references are dropped so the entries can be released
+	var labels []mongodbatlas.Label
+	for _, label := range user.Labels {
+		if label.Key != labelAccessList && label.Key != labelRevokedAt {
+			labels = append(labels, label)
+		}
+	}
+	labels = append(labels, mongodbatlas.Label{Key: labelRevokedAt, Value: now.Format(time.RFC3339)})
+
+	update := &mongodbatlas.DatabaseUser{
+		DatabaseName:    user.DatabaseName,
+		X509Type:        user.X509Type,
+		AWSIAMType:      user.AWSIAMType,
+		LDAPAuthType:    user.LDAPAuthType,
+		OIDCAuthType:    user.OIDCAuthType,
+		Roles:           []mongodbatlas.Role{revokedRole},
+		Scopes:          user.Scopes,
+		Labels:          labels,
+		DeleteAfterDate: now.AddDate(0, 0, retainDays).Format(time.RFC3339),
+	}
+
+	// Only SCRAM users have a password that can be scrambled
+	if update.GetAuthDB() == "admin" {
+		password, err := base62.Random(scrambledPasswordLength)
+		if err != nil {
+			return fmt.Errorf("unable to generate password: %w", err)
+		}
+		update.Password = password
+	}
+
+	_, _, err := client.DatabaseUsers.Update(ctx, projectID, user.Username, update)
+	if err != nil {
+		return fmt.Errorf("error soft revoking user: %w", err)
+	}
+	return nil
+}
+
+// isRevokedUser reports whether the user was soft revoked.
+func isRevokedUser(user *mongodbatlas.DatabaseUser) bool {
+	_, ok := labelValue(user, labelRevokedAt)
+	return ok
+}
@@ -0,0 +1,54 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	dbplugin "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/atlas/mongodbatlas"
+)
+
+func TestDeleteUser_SoftRevoke(t *testing.T) {
+	atlas := newFakeAtlas(t)
+	db := atlas.newTestDB(t, nil)
+
+	statement := `{"roles": [{"databaseName":"app","roleName":"readWrite"}], "access_list": ["198.51.100.7"]}`
+	resp, err := db.NewUser(context.Background(), dbplugin.NewUserRequest{
+		UsernameConfig: dbplugin.UsernameMetadata{DisplayName: "token", RoleName: "app"},
+		Statements:     dbplugin.Statements{Commands: []string{statement}},
+		Password:       "password",
+		Expiration:     time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	_, err = db.DeleteUser(context.Background(), dbplugin.DeleteUserRequest{
+		Username:   resp.Username,
+		Statements: dbplugin.Statements{Commands: []string{`{"soft_revoke": true, "retain_days": 3}`}},
+	})
+	require.NoError(t, err)
+
+	user := atlas.user("project", resp.Username)
+	require.NotNil(t, user)
+	require.Equal(t, []mongodbatlas.Role{revokedRole}, user.Roles)
+	require.NotEqual(t, "password", user.Password)
+	require.True(t, isRevokedUser(user))
+	require.Empty(t, userAccessList(user))
+
+	deleteAfter, err := time.Parse(time.RFC3339, user.DeleteAfterDate)
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now().AddDate(0, 0, 3), deleteAfter, time.Minute)
+
+	// Access list entries are released with the lease
+	require.Nil(t, atlas.accessListEntry("project", "198.51.100.7"))
+
+	_, err = db.DeleteUser(context.Background(), dbplugin.DeleteUserRequest{
+		Username:   resp.Username,
+		Statements: dbplugin.Statements{Commands: []string{`{"soft_revoke": true, "retain_days": 30}`}},
+	})
+	require.ErrorContains(t, err, "retain_days must be between 1 and 7")
+}
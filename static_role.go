@@ -0,0 +1,72 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"fmt"
+
+	"go.mongodb.org/atlas/mongodbatlas"
+)
+
+// labelStaticRole marks users onboarded as Vault static roles. They are
+// managed by the plugin but don't count towards quotas and are never adopted
+// for dynamic credentials.
+const labelStaticRole = "vault-static-role"
+
+// isStaticRoleUser reports whether the user was onboarded as a static role.
+func isStaticRoleUser(user *mongodbatlas.DatabaseUser) bool {
+	_, ok := labelValue(user, labelStaticRole)
+	return ok
+}
+
+// onboardStaticRole checks that an existing user can be managed by a static
+// role the first time its password is rotated, and adds the ownership labels
+// to the update request. Users already onboarded are left as they are.
+func (c *mongoDBAtlasConnectionProducer) onboardStaticRole(request, user *mongodbatlas.DatabaseUser) error {
+	if isStaticRoleUser(user) {
+		return nil
+	}
+
+	if err := checkStaticRoleUser(user); err != nil {
+		return err
+	}
+
+	labels := append([]mongodbatlas.Label{}, user.Labels...)
+	if value, ok := labelValue(user, labelManagedBy); !ok || value != labelManagedByValue {
+		labels = append(labels, mongodbatlas.Label{Key: labelManagedBy, Value: labelManagedByValue})
+	}
+	labels = append(labels, mongodbatlas.Label{Key: labelStaticRole, Value: "true"})
+
+	// Rotation statements reconcile labels on top of the ones set here
+	user.Labels = labels
+	request.Labels = labels
+
+	c.logger.Info("onboarding existing user as a static role", "username", user.Username)
+	return nil
+}
+
+// checkStaticRoleUser returns an error explaining why a user can't have its
+// password rotated by a static role. Which projects a static role may reach
+// is decided when the user is looked up.
+func checkStaticRoleUser(user *mongodbatlas.DatabaseUser) error {
+	for _, auth := range []struct {
+		name, value string
+	}{
+		{"X.509", user.X509Type},
+		{"AWS IAM", user.AWSIAMType},
+		{"LDAP", user.LDAPAuthType},
+		{"OIDC", user.OIDCAuthType},
+	} {
+		if auth.value != "" && auth.value != "NONE" {
+			return fmt.Errorf("user %q uses %s authentication (%s), which has no password; "+
+				"only SCRAM users can be managed by static roles", user.Username, auth.name, auth.value)
+		}
+	}
+
+	if user.DatabaseName != "" && user.DatabaseName != "admin" {
+		return fmt.Errorf("user %q authenticates against the %q database; "+
+			"only SCRAM users authenticating against admin can be managed by static roles", user.Username, user.DatabaseName)
+	}
+	return nil
+}
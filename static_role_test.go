@@ -0,0 +1,81 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"context"
+	"testing"
+
+	dbplugin "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/atlas/mongodbatlas"
+)
+
+func TestUpdateUser_OnboardStaticRole(t *testing.T) {
+	atlas := newFakeAtlas(t)
+	db := atlas.newTestDB(t, nil)
+	atlas.putUser("project", &mongodbatlas.DatabaseUser{
+		Username:     "reporting",
+		DatabaseName: "admin",
+		Password:     "original",
+		Labels:       []mongodbatlas.Label{{Key: "team", Value: "data"}},
+	})
+
+	rotate := func(username string) error {
+		_, err := db.UpdateUser(context.Background(), dbplugin.UpdateUserRequest{
+			Username: username,
+			Password: &dbplugin.ChangePassword{NewPassword: "rotated"},
+		})
+		return err
+	}
+
+	require.NoError(t, rotate("reporting"))
+	user := atlas.user("project", "reporting")
+	require.Equal(t, "rotated", user.Password)
+	require.ElementsMatch(t, []mongodbatlas.Label{
+		{Key: "team", Value: "data"},
+		{Key: labelManagedBy, Value: labelManagedByValue},
+		{Key: labelStaticRole, Value: "true"},
+	}, user.Labels)
+
+	// Onboarded users are left alone on later rotations
+	require.NoError(t, rotate("reporting"))
+	require.Len(t, atlas.user("project", "reporting").Labels, 3)
+
+	require.ErrorContains(t, rotate("missing"), `user "missing" does not exist in project "project"`)
+}
+
+func TestCheckStaticRoleUser(t *testing.T) {
+	tests := map[string]struct {
+		user *mongodbatlas.DatabaseUser
+		err  string
+	}{
+		"scram": {
+			user: &mongodbatlas.DatabaseUser{Username: "u", DatabaseName: "admin", GroupID: "project", X509Type: "NONE"},
+		},
+		"x509": {
+			user: &mongodbatlas.DatabaseUser{Username: "u", DatabaseName: "$external", X509Type: "MANAGED"},
+			err:  `user "u" uses X.509 authentication (MANAGED), which has no password`,
+		},
+		"iam": {
+			user: &mongodbatlas.DatabaseUser{Username: "u", DatabaseName: "$external", AWSIAMType: "ROLE"},
+			err:  "uses AWS IAM authentication",
+		},
+		"ldap": {
+			user: &mongodbatlas.DatabaseUser{Username: "u", DatabaseName: "$external", LDAPAuthType: "USER"},
+			err:  "uses LDAP authentication",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := checkStaticRoleUser(test.user)
+			if test.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, test.err)
+		})
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	glob "github.com/ryanuber/go-glob"
+)
+
+// regexPatternPrefix marks a policy pattern as a regular expression rather
+// than a glob. Regular expressions must match the whole value.
+const regexPatternPrefix = "regex:"
+
+// subjectPolicy restricts the certificate subjects that may be used as
+// usernames for client certificate credentials. It can be set on the
+// connection as well as in creation statements; both are enforced.
+type subjectPolicy struct {
+	AllowedSubjects     []string `json:"allowed_subjects,omitempty" structs:"allowed_subjects" mapstructure:"allowed_subjects"`
+	DeniedSubjects      []string `json:"denied_subjects,omitempty" structs:"denied_subjects" mapstructure:"denied_subjects"`
+	RequiredSubjectRDNs []string `json:"required_subject_rdns,omitempty" structs:"required_subject_rdns" mapstructure:"required_subject_rdns"`
+	MaxSubjectLength    int      `json:"max_subject_length,omitempty" structs:"max_subject_length" mapstructure:"max_subject_length"`
+}
+
+// validate checks that every pattern in the policy can be compiled.
+func (p subjectPolicy) validate() error {
+	for _, pattern := range append(append([]string{}, p.AllowedSubjects...), p.DeniedSubjects...) {
+		if _, err := matchPattern(pattern, ""); err != nil {
+			return err
+		}
+	}
+	if p.MaxSubjectLength < 0 {
+		return errors.New("max_subject_length must not be negative")
+	}
+	return nil
+}
+
+// check returns an error describing why the subject violates the policy.
+func (p subjectPolicy) check(subject string) error {
+	if p.MaxSubjectLength > 0 && len(subject) > p.MaxSubjectLength {
+		return fmt.Errorf("subject is %d characters long, the maximum allowed is %d", len(subject), p.MaxSubjectLength)
+	}
+
+	denied, err := matchAny(p.DeniedSubjects, subject)
+	if err != nil {
+		return err
+	}
+	if denied {
+		return fmt.Errorf("subject %q matches a denied pattern", subject)
+	}
+
+	if len(p.AllowedSubjects) > 0 {
+		allowed, err := matchAny(p.AllowedSubjects, subject)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return fmt.Errorf("subject %q does not match any allowed pattern", subject)
+		}
+	}
+
+	if len(p.RequiredSubjectRDNs) > 0 {
+		rdns, err := parseDistinguishedName(subject)
+		if err != nil {
+			return fmt.Errorf("unable to parse subject %q: %w", subject, err)
+		}
+		for _, required := range p.RequiredSubjectRDNs {
+			if _, ok := rdns[strings.ToUpper(required)]; !ok {
+				return fmt.Errorf("subject %q is missing required attribute %q", subject, required)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkSubject enforces the connection and statement subject policies.
+func checkSubject(subject string, policies ...subjectPolicy) error {
+	if subject == "" {
+		return errors.New("subject is required for client certificate credentials")
+	}
+	if _, err := parseDistinguishedName(subject); err != nil {
+		return fmt.Errorf("invalid subject %q: %w", subject, err)
+	}
+	for _, p := range policies {
+		if err := p.check(subject); err != nil {
+			return fmt.Errorf("subject policy violation: %w", err)
+		}
+	}
+	return nil
+}
+
+// matchPattern matches value against a glob, or against a regular expression
+// if the pattern is prefixed with "regex:".
+func matchPattern(pattern, value string) (bool, error) {
+	if expr, ok := strings.CutPrefix(pattern, regexPatternPrefix); ok {
+		re, err := regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			return false, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		return re.MatchString(value), nil
+	}
+	return glob.Glob(pattern, value), nil
+}
+
+// parseDistinguishedName parses an RFC 4514 string into a map of upper-cased
+// attribute types to their values.
+func parseDistinguishedName(dn string) (map[string][]string, error) {
+	attrs := make(map[string][]string)
+
+	var (
+		buf     strings.Builder
+		attr    string
+		escaped bool
+	)
+	flush := func() error {
+		value := strings.TrimSpace(buf.String())
+		buf.Reset()
+		if attr == "" || value == "" {
+			return errors.New("empty attribute type or value")
+		}
+		attrs[attr] = append(attrs[attr], value)
+		attr = ""
+		return nil
+	}
+
+	for _, r := range dn {
+		switch {
+		case escaped:
+			buf.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			buf.WriteRune(r)
+			escaped = true
+		case r == '=' && attr == "":
+			attr = strings.ToUpper(strings.TrimSpace(buf.String()))
+			buf.Reset()
+		case r == ',' || r == '+':
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	if escaped {
+		return nil, errors.New("trailing escape character")
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return attrs, nil
+}
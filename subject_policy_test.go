@@ -0,0 +1,135 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"context"
+	"testing"
+
+	dbplugin "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubjectPolicy_Check(t *testing.T) {
+	tests := map[string]struct {
+		policy  subjectPolicy
+		subject string
+		wantErr string
+	}{
+		"empty policy allows anything": {
+			subject: "CN=anything",
+		},
+		"glob allowed": {
+			policy:  subjectPolicy{AllowedSubjects: []string{"CN=*,OU=apps,O=Example"}},
+			subject: "CN=billing,OU=apps,O=Example",
+		},
+		"glob not allowed": {
+			policy:  subjectPolicy{AllowedSubjects: []string{"CN=*,OU=apps,O=Example"}},
+			subject: "CN=billing,OU=humans,O=Example",
+			wantErr: "does not match any allowed pattern",
+		},
+		"regex allowed": {
+			policy:  subjectPolicy{AllowedSubjects: []string{`regex:CN=svc-[a-z]+`}},
+			subject: "CN=svc-billing",
+		},
+		"regex must match the whole subject": {
+			policy:  subjectPolicy{AllowedSubjects: []string{`regex:CN=svc-[a-z]+`}},
+			subject: "CN=svc-billing,OU=admins",
+			wantErr: "does not match any allowed pattern",
+		},
+		"denied wins over allowed": {
+			policy: subjectPolicy{
+				AllowedSubjects: []string{"*"},
+				DeniedSubjects:  []string{"*OU=admins*"},
+			},
+			subject: "CN=root,OU=admins",
+			wantErr: "matches a denied pattern",
+		},
+		"required RDN present": {
+			policy:  subjectPolicy{RequiredSubjectRDNs: []string{"ou"}},
+			subject: "CN=billing,OU=apps",
+		},
+		"required RDN missing": {
+			policy:  subjectPolicy{RequiredSubjectRDNs: []string{"OU"}},
+			subject: "CN=billing",
+			wantErr: `missing required attribute "OU"`,
+		},
+		"too long": {
+			policy:  subjectPolicy{MaxSubjectLength: 10},
+			subject: "CN=a-very-long-name",
+			wantErr: "maximum allowed is 10",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, tt.policy.validate())
+
+			err := tt.policy.check(tt.subject)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseDistinguishedName(t *testing.T) {
+	rdns, err := parseDistinguishedName(`CN=Smith\, John+UID=jsmith,OU=apps, O=Example`)
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{
+		"CN":  {`Smith\, John`},
+		"UID": {"jsmith"},
+		"OU":  {"apps"},
+		"O":   {"Example"},
+	}, rdns)
+
+	for _, dn := range []string{"", "CN", "CN=", "=value", `CN=foo\`} {
+		_, err := parseDistinguishedName(dn)
+		require.Error(t, err, dn)
+	}
+}
+
+func TestNewUser_SubjectPolicy(t *testing.T) {
+	atlas := newFakeAtlas(t)
+	atlas.customerX509["project"] = testCACertificate(t, "ca")
+
+	db := atlas.newTestDB(t, map[string]interface{}{
+		"denied_subjects": "*OU=admins*",
+	})
+
+	statement := `{"database_name": "$external", "x509Type": "CUSTOMER", "roles": [{"databaseName":"admin","roleName":"read"}], "required_subject_rdns": ["OU"]}`
+
+	tests := map[string]struct {
+		subject string
+		wantErr string
+	}{
+		"allowed":              {subject: "CN=app,OU=apps"},
+		"denied by connection": {subject: "CN=root,OU=admins", wantErr: "matches a denied pattern"},
+		"denied by statement":  {subject: "CN=app", wantErr: "missing required attribute"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			atlas.requests = nil
+
+			_, err := db.NewUser(context.Background(), dbplugin.NewUserRequest{
+				CredentialType: dbplugin.CredentialTypeClientCertificate,
+				Subject:        tt.subject,
+				Statements: dbplugin.Statements{
+					Commands: []string{statement},
+				},
+			})
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				require.NotNil(t, atlas.user("project", tt.subject))
+				return
+			}
+
+			require.ErrorContains(t, err, tt.wantErr)
+			require.Empty(t, atlas.requests, "policy must be enforced before any Atlas call")
+		})
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// rollbackTimeout bounds the cleanup of an operation that failed, which
+// runs even if the operation itself ran out of time.
+const rollbackTimeout = 30 * time.Second
+
+// timeoutConfig bounds how long each kind of operation may take, including
+// waiting for deployment and verification. Zero leaves only the deadline of
+// the request from Vault.
+type timeoutConfig struct {
+	CreateTimeout time.Duration `json:"create_timeout" structs:"create_timeout" mapstructure:"create_timeout"`
+	UpdateTimeout time.Duration `json:"update_timeout" structs:"update_timeout" mapstructure:"update_timeout"`
+	DeleteTimeout time.Duration `json:"delete_timeout" structs:"delete_timeout" mapstructure:"delete_timeout"`
+}
+
+func (t timeoutConfig) validate() error {
+	if t.CreateTimeout < 0 || t.UpdateTimeout < 0 || t.DeleteTimeout < 0 {
+		return errors.New("create_timeout, update_timeout and delete_timeout must not be negative")
+	}
+	return nil
+}
+
+// atlasChanges records whether an operation sent any request that changes
+// something in Atlas.
+type atlasChanges struct {
+	sent atomic.Bool
+}
+
+type atlasChangesKey struct{}
+
+// operationContext bounds an operation by the timeout, if set, and tracks
+// the changes it sends to Atlas so a failure can say whether any may have
+// been applied.
+func operationContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc, *atlasChanges) {
+	changes := &atlasChanges{}
+	ctx = context.WithValue(ctx, atlasChangesKey{}, changes)
+	if timeout == 0 {
+		ctx, cancel := context.WithCancel(ctx)
+		return ctx, cancel, changes
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, cancel, changes
+}
+
+// operationError explains an error caused by the operation running out of
+// time or being canceled. Once a change was sent, Atlas may have applied it
+// even though no response arrived.
+func operationError(ctx context.Context, operation string, changes *atlasChanges, err error) error {
+	if err == nil || ctx.Err() == nil {
+		return err
+	}
+
+	reason := "was canceled"
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		reason = "timed out"
+	}
+	if changes.sent.Load() {
+		return fmt.Errorf("%s %s after changes were sent to Atlas, which may have been applied; "+
+			"check the user in Atlas before retrying: %w", operation, reason, err)
+	}
+	return fmt.Errorf("%s %s before any changes were sent to Atlas: %w", operation, reason, err)
+}
+
+// cleanupContext returns a context for undoing a failed operation, which
+// outlives the operation's own deadline.
+func cleanupContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), rollbackTimeout)
+}
+
+// changeTrackingTransport marks the operation a request belongs to as having
+// sent changes to Atlas before the request goes out.
+type changeTrackingTransport struct {
+	base http.RoundTripper
+}
+
+func (t *changeTrackingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		if changes, ok := req.Context().Value(atlasChangesKey{}).(*atlasChanges); ok {
+			changes.sent.Store(true)
+		}
+	}
+	return t.base.RoundTrip(req)
+}
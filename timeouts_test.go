@@ -0,0 +1,107 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	dbplugin "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/atlas/mongodbatlas"
+)
+
+func TestNewUser_CreateTimeout(t *testing.T) {
+	atlas := newFakeAtlas(t)
+	atlas.addCluster("project", "cluster0")
+	atlas.deployPolls = 1000
+
+	db := atlas.newTestDB(t, map[string]interface{}{
+		"create_timeout":      "50ms",
+		"wait_for_deployment": true,
+		"deployment_clusters": "cluster0",
+	})
+	db.deploymentPollInterval = time.Millisecond
+
+	// The user was created before the deadline, so it is rolled back
+	_, err := db.NewUser(context.Background(), dbplugin.NewUserRequest{
+		Statements: dbplugin.Statements{Commands: []string{`{"roles": [{"databaseName":"app","roleName":"read"}]}`}},
+		Password:   "password",
+	})
+	require.ErrorContains(t, err, "creating the user timed out after changes were sent to Atlas, which may have been applied")
+	require.Empty(t, atlas.users["project"])
+}
+
+func TestNewUser_TimeoutBeforeChanges(t *testing.T) {
+	atlas := newFakeAtlas(t)
+	atlas.latency = 100 * time.Millisecond
+
+	db := atlas.newTestDB(t, map[string]interface{}{
+		"create_timeout":        "20ms",
+		"max_users_per_project": 10,
+	})
+
+	// Counting users for the quota is the first request
+	_, err := db.NewUser(context.Background(), dbplugin.NewUserRequest{
+		Statements: dbplugin.Statements{Commands: []string{testMongoDBAtlasRole}},
+		Password:   "password",
+	})
+	require.ErrorContains(t, err, "creating the user timed out before any changes were sent to Atlas")
+}
+
+func TestUpdateUser_HonorsContext(t *testing.T) {
+	atlas := newFakeAtlas(t)
+	atlas.putUser("project", &mongodbatlas.DatabaseUser{
+		Username:     "v-app",
+		DatabaseName: "admin",
+		Labels:       managedUserLabels("app"),
+	})
+	db := atlas.newTestDB(t, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := db.UpdateUser(ctx, dbplugin.UpdateUserRequest{
+		Username: "v-app",
+		Password: &dbplugin.ChangePassword{NewPassword: "rotated"},
+	})
+	require.ErrorContains(t, err, "changing the password was canceled before any changes were sent to Atlas")
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestDeleteUser_DeleteTimeout(t *testing.T) {
+	atlas := newFakeAtlas(t)
+	atlas.putUser("project", &mongodbatlas.DatabaseUser{
+		Username:     "v-app",
+		DatabaseName: "admin",
+		Labels:       managedUserLabels("app"),
+	})
+	atlas.addCluster("project", "cluster0")
+	atlas.deployPolls = 1000
+
+	db := atlas.newTestDB(t, map[string]interface{}{
+		"delete_timeout":      "50ms",
+		"wait_for_deployment": true,
+		"deployment_clusters": "cluster0",
+	})
+	db.deploymentPollInterval = time.Millisecond
+
+	_, err := db.DeleteUser(context.Background(), dbplugin.DeleteUserRequest{Username: "v-app"})
+	require.ErrorContains(t, err, "deleting the user timed out after changes were sent to Atlas")
+	require.Nil(t, atlas.user("project", "v-app"))
+}
+
+func TestInitialize_InvalidTimeouts(t *testing.T) {
+	db := new()
+	_, err := db.Initialize(context.Background(), dbplugin.InitializeRequest{
+		Config: map[string]interface{}{
+			"public_key":     "public",
+			"private_key":    "private",
+			"project_id":     "project",
+			"update_timeout": "-1s",
+		},
+	})
+	require.ErrorContains(t, err, "create_timeout, update_timeout and delete_timeout must not be negative")
+}
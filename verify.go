@@ -0,0 +1,134 @@
+// Copyright IBM Corp. 2019, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package mongodbatlas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/database/helper/dbutil"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+const (
+	defaultVerifyTimeout  = time.Minute
+	defaultVerifyInterval = 2 * time.Second
+)
+
+// verifyConfig enables authenticating against a cluster with newly issued
+// credentials before they are handed out.
+type verifyConfig struct {
+	// VerifyConnectionURL is a mongodb:// or mongodb+srv:// connection string.
+	// It may contain {{username}} and {{password}} placeholders; otherwise
+	// the credentials are supplied separately.
+	VerifyConnectionURL string        `json:"verify_connection_url" structs:"verify_connection_url" mapstructure:"verify_connection_url"`
+	VerifyTimeout       time.Duration `json:"verify_timeout" structs:"verify_timeout" mapstructure:"verify_timeout"`
+}
+
+func (v verifyConfig) validate() error {
+	if v.VerifyTimeout < 0 {
+		return errors.New("verify_timeout must not be negative")
+	}
+	if v.VerifyConnectionURL == "" {
+		return nil
+	}
+	// Validate with placeholder credentials so the URL never ends up in errors
+	uri := verifyURI(v.VerifyConnectionURL, "username", "password")
+	if !strings.HasPrefix(uri, "mongodb://") && !strings.HasPrefix(uri, "mongodb+srv://") {
+		return errors.New("verify_connection_url must start with mongodb:// or mongodb+srv://")
+	}
+	if _, err := url.Parse(uri); err != nil {
+		return errors.New("verify_connection_url is not a valid URL")
+	}
+	return nil
+}
+
+// verifies reports whether users in the project can be verified. The
+// connection URL points at a cluster of the configured project, so users in
+// other projects don't exist there.
+func (c *mongoDBAtlasConnectionProducer) verifies(projectID string) bool {
+	return c.VerifyConnectionURL != "" && projectID == c.projectID()
+}
+
+// verifyCredentials retries authenticating with the credentials of a user in
+// the project until it succeeds or verify_timeout passes. It is a no-op if no
+// connection URL is set or the user is in another project.
+func (c *mongoDBAtlasConnectionProducer) verifyCredentials(ctx context.Context, projectID, username, password string) error {
+	if !c.verifies(projectID) {
+		return nil
+	}
+
+	timeout := c.VerifyTimeout
+	if timeout == 0 {
+		timeout = defaultVerifyTimeout
+	}
+	interval := c.verifyInterval
+	if interval == 0 {
+		interval = defaultVerifyInterval
+	}
+	ping := c.pingCluster
+	if ping == nil {
+		ping = pingCluster
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		lastErr = ping(ctx, c.VerifyConnectionURL, username, password)
+		if lastErr == nil {
+			return nil
+		}
+		lastErr = c.redact(lastErr, password)
+		c.logger.Debug("credentials not usable yet", "username", username, "attempt", attempt, "error", lastErr)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("credentials for user %q could not be verified against the cluster within %s: %w",
+				username, timeout, lastErr)
+		case <-ticker.C:
+		}
+	}
+}
+
+// pingCluster connects to the cluster with the credentials and pings it.
+func pingCluster(ctx context.Context, connectionURL, username, password string) error {
+	opts := options.Client().ApplyURI(verifyURI(connectionURL, username, password))
+	if !strings.Contains(connectionURL, "{{username}}") {
+		opts.SetAuth(options.Credential{
+			AuthSource: "admin",
+			Username:   username,
+			Password:   password,
+		})
+	}
+
+	// Connection string parsing errors can echo the URL, and with it the
+	// password, so they are not passed on
+	client, err := mongo.Connect(ctx, opts)
+	if err != nil {
+		return errors.New("unable to connect to cluster")
+	}
+	defer client.Disconnect(context.Background())
+
+	return client.Ping(ctx, readpref.Primary())
+}
+
+// verifyURI fills in the {{username}} and {{password}} placeholders, escaped
+// for use in the userinfo part of the URL.
+func verifyURI(connectionURL, username, password string) string {
+	return dbutil.QueryHelper(connectionURL, map[string]string{
+		"username": url.User(username).String(),
+		"password": strings.TrimPrefix(url.UserPassword("", password).String(), ":"),
+	})
+}